@@ -0,0 +1,187 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// EffectKind is the kind of the animation.
+type EffectKind int
+
+const (
+	EffectFlight    EffectKind = iota // the projectile flying to the target.
+	EffectSplash                      // the miss.
+	EffectExplosion                   // the hit of a ship or a mine.
+	EffectSink                        // the sunk ship cell going under water.
+	EffectShake                       // the whole screen shaking.
+)
+
+// effectTicks are the durations of the effects.
+var effectTicks = map[EffectKind]int64{
+	EffectFlight:    gameTPS * 3 / 10,
+	EffectSplash:    gameTPS * 2 / 5,
+	EffectExplosion: gameTPS / 2,
+	EffectSink:      gameTPS * 4 / 5,
+	EffectShake:     gameTPS * 2 / 5,
+}
+
+// effectKinds are the effects of the cells changed by the shot.
+var effectKinds = map[Cell]EffectKind{
+	CellMiss:  EffectSplash,
+	CellFire:  EffectExplosion,
+	CellSunk:  EffectSink,
+	CellBlast: EffectExplosion,
+}
+
+// Effect is the animation of the shot, played while the input is blocked.
+type Effect struct {
+	Kind   EffectKind
+	Side   Side // of the board, or the shooter for the flight.
+	At     XY   // the cell, or the target of the flight.
+	Start  int64
+	Before Cell // the cell seen by the player until the effect starts.
+}
+
+// progress returns how much of the effect is played, from 0 to 1.
+func (e *Effect) progress(tick int64) float32 {
+	return min(float32(tick-e.Start)/float32(effectTicks[e.Kind]), 1)
+}
+
+// animating returns whether the effects are still played.
+func (g *Game) animating() bool {
+	return len(g.Effects) > 0
+}
+
+// animateShot adds the effects of the shot by the side at xy,
+// given the board before the shot as seen by its owner and by the player.
+func (g *Game) animateShot(side Side, xy XY, before, seen, after *View) {
+	g.Effects = append(g.Effects, &Effect{Kind: EffectFlight, Side: side, At: xy, Start: g.Tick})
+	g.animateCells(side.opponent(), g.Tick+effectTicks[EffectFlight], before, seen, after)
+}
+
+// animateCells adds the effects of the cells of the board of the side changed at the tick,
+// given the board before as seen by its owner and by the player.
+func (g *Game) animateCells(side Side, start int64, before, seen, after *View) {
+	shake := false
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			c := after.Cells[y][x]
+			kind, ok := effectKinds[c]
+			if !ok || c == before.Cells[y][x] {
+				continue
+			}
+			g.Effects = append(g.Effects, &Effect{Kind: kind, Side: side, At: XY{x, y}, Start: start, Before: seen.Cells[y][x]})
+			shake = shake || c == CellBlast || c == CellSunk || (c == CellFire && side == SideSelf)
+		}
+	}
+	if shake {
+		g.Effects = append(g.Effects, &Effect{Kind: EffectShake, Start: start})
+	}
+}
+
+// effectsEnd returns the tick when all the effects are played.
+func (g *Game) effectsEnd() int64 {
+	end := g.Tick
+	for _, e := range g.Effects {
+		end = max(end, e.Start+effectTicks[e.Kind])
+	}
+	return end
+}
+
+// updateEffects drops the effects played.
+func (g *Game) updateEffects() {
+	g.Effects = slices.DeleteFunc(g.Effects, func(e *Effect) bool {
+		return e.Start+effectTicks[e.Kind] <= g.Tick
+	})
+}
+
+// pendingCell returns the cell of the board seen by the player until its effect starts.
+func (g *Game) pendingCell(side Side, xy XY) (Cell, bool) {
+	for _, e := range g.Effects {
+		if e.Kind != EffectFlight && e.Kind != EffectShake && e.Side == side && e.At == xy && g.Tick < e.Start {
+			return e.Before, true
+		}
+	}
+	return 0, false
+}
+
+// shakeOffset returns how far the screen is moved by the shake now.
+func (g *Game) shakeOffset() (int, int) {
+	for _, e := range g.Effects {
+		if e.Kind == EffectShake && g.Tick >= e.Start {
+			a := float64(cellSize/8) * float64(1-e.progress(g.Tick))
+			return int(a * math.Sin(float64(g.Tick)*2.5)), int(a * math.Cos(float64(g.Tick)*3.1))
+		}
+	}
+	return 0, 0
+}
+
+// cellCenter returns the screen position of the center of the cell.
+func (g *Game) cellCenter(xy XY, side Side) (float32, float32) {
+	x, y := g.Grid.cellOrigin(xy.X, xy.Y, side)
+	return x + cellSizeF/2, y + cellSizeF/2
+}
+
+// drawEffects draws the effects started, and the flames of the burning ships.
+func (g *Game) drawEffects(screen *ebiten.Image, views [2]*View) {
+	for side, v := range views {
+		for y := 0; y < Ncells; y++ {
+			for x := 0; x < Ncells; x++ {
+				if v.Cells[y][x] == CellFire {
+					g.drawFlames(screen, XY{x, y}, Side(side))
+				}
+			}
+		}
+	}
+	for _, e := range g.Effects {
+		if g.Tick < e.Start {
+			continue
+		}
+		p := e.progress(g.Tick)
+		cx, cy := g.cellCenter(e.At, e.Side)
+		switch e.Kind {
+		case EffectFlight:
+			// The projectile flies in the arc from the middle of the shooter's board.
+			sx, sy := g.cellCenter(XY{Ncells / 2, Ncells / 2}, e.Side)
+			x := sx + (cx-sx)*p
+			y := sy + (cy-sy)*p - float32(math.Sin(float64(p)*math.Pi))*cellSizeF*2
+			vector.DrawFilledCircle(screen, x, y, cellSizeF/8, color.RGBA(g.Theme.Cells[CellMine].Mark), true)
+		case EffectSplash:
+			a := uint8(0xff * (1 - p))
+			vector.StrokeCircle(screen, cx, cy, cellSizeF/2*p, 2, color.RGBA{0xff, 0xff, 0xff, a}, true)
+			vector.StrokeCircle(screen, cx, cy, cellSizeF/4*p, 2, color.RGBA{0xcc, 0xdd, 0xff, a}, true)
+		case EffectExplosion:
+			a := uint8(0xff * (1 - p))
+			vector.DrawFilledCircle(screen, cx, cy, cellSizeF/2*p, color.RGBA{0xff, 0xaa, 0x22, a}, true)
+			// The debris flies apart.
+			for i := 0; i < 8; i++ {
+				angle := float64(i)*math.Pi/4 + float64(e.At.X*Ncells+e.At.Y)
+				r := cellSizeF * 0.8 * p
+				px := cx + r*float32(math.Cos(angle))
+				py := cy + r*float32(math.Sin(angle))
+				vector.DrawFilledCircle(screen, px, py, 2, color.RGBA{0xff, 0x66, 0x00, a}, true)
+			}
+		case EffectSink:
+			// The water rises over the wreck.
+			x, y := g.Grid.cellOrigin(e.At.X, e.At.Y, e.Side)
+			h := cellSizeF * (1 - p)
+			vector.DrawFilledRect(screen, x, y+cellSizeF-h, cellSizeF, h, color.RGBA(g.Theme.Cells[CellShip].Fill), false)
+		}
+	}
+}
+
+// drawFlames draws the flickering flames over the burning cell.
+func (g *Game) drawFlames(screen *ebiten.Image, xy XY, side Side) {
+	cx, cy := g.cellCenter(xy, side)
+	for i := int64(0); i < 3; i++ {
+		// Every flame rises and fades in its own phase.
+		phase := float32((g.Tick+i*7+int64(xy.X*3+xy.Y*5))%gameTPS) / gameTPS
+		x := cx + float32(i-1)*cellSizeF/5
+		y := cy + cellSizeF/4 - phase*cellSizeF/2
+		vector.DrawFilledCircle(screen, x, y, cellSizeF/10*(1-phase)+1, color.RGBA{0xff, 0xaa, 0x22, uint8(0xcc * (1 - phase))}, true)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Announcer tells what happens in the game to the players who cannot see the board:
+// aloud with the text-to-speech, and line by line to the log, like for a screen reader.
+type Announcer struct {
+	Speech *Speech   // nil if there is no text-to-speech.
+	Log    io.Writer // nil if there is no log.
+	Last   string    // the last announcement, shown in the terminal.
+
+	pending  []string
+	cursor   XY     // where the cursor was announced last.
+	message  string // the message announced last.
+	menuLine string // the row of the menu announced last.
+}
+
+// NewAnnouncer finds the text-to-speech, and opens the log at the path, if any.
+func NewAnnouncer(path string) (*Announcer, error) {
+	a := &Announcer{cursor: XY{-1, -1}}
+	var err error
+	if a.Speech, err = NewSpeech(); err != nil {
+		log.Printf("No speech: %v", err)
+	}
+	if path != "" {
+		if a.Log, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// announce adds the text to the next announcement, if the game has the announcer.
+func (g *Game) announce(format string, args ...any) {
+	if g.Announcer != nil {
+		g.Announcer.pending = append(g.Announcer.pending, fmt.Sprintf(format, args...))
+	}
+}
+
+// flushAnnouncements tells all that has happened since the last call: the shots,
+// the new message and where the cursor has moved.
+func (g *Game) flushAnnouncements() {
+	a := g.Announcer
+	if a == nil {
+		return
+	}
+	msg := g.Message
+	if g.Error != nil {
+		msg = g.Error.Error()
+	}
+	if msg != a.message {
+		a.message = msg
+		g.announce("%s", msg)
+	}
+	if g.Menu == nil {
+		a.menuLine = ""
+	} else if line := g.Menu.lines(g)[g.Menu.Row]; line != a.menuLine {
+		a.menuLine = line
+		g.announce("%s", line)
+	}
+	side, xy := g.cursorCell()
+	if xy != a.cursor {
+		a.cursor = xy
+		// The cursor goes first, as it moves before the shot.
+		a.pending = append([]string{g.cellText(side, xy)}, a.pending...)
+		g.playCue(xy)
+	}
+	if len(a.pending) == 0 {
+		return
+	}
+	a.Last = strings.Join(a.pending, ". ")
+	a.pending = a.pending[:0]
+	if a.Log != nil {
+		if _, err := fmt.Fprintln(a.Log, a.Last); err != nil {
+			log.Printf("Announcements are not logged: %v", err)
+			a.Log = nil
+		}
+	}
+	if a.Speech != nil && g.Settings.Speech {
+		// The new announcement interrupts the old one, like in the screen readers.
+		a.Speech.say(a.Last)
+	}
+}
+
+// cursorCell returns the board and the cell under the cursor of the player.
+func (g *Game) cursorCell() (Side, XY) {
+	if g.Moving || g.PlacingMines {
+		return SideSelf, g.CursorOwn
+	}
+	return SidePeer, g.CursorSelf
+}
+
+// cellText describes the cell as the player sees it, like "C5, miss".
+func (g *Game) cellText(side Side, xy XY) string {
+	c := g.Boards[side].view(SideSelf).Cells[xy.Y][xy.X]
+	return fmt.Sprintf("%s, %s", g.Lang.cell(xy), g.tr(cellNames[c]))
+}
+
+// shotText describes the shot of the side, like "You fired at C5: hit, sunk a 3-ship".
+func (g *Game) shotText(side Side, w Weapon, xy XY, before, after *View) string {
+	at := g.Lang.cell(xy)
+	weapon := strings.ToLower(g.tr(w.String()))
+	var what string
+	switch {
+	case side == SideSelf && w == WeaponShot:
+		what = g.tr("You fired at %s", at)
+	case side == SideSelf:
+		what = g.tr("You fired the %s at %s", weapon, at)
+	case w == WeaponShot:
+		what = g.tr("The peer fired at %s", at)
+	default:
+		what = g.tr("The peer fired the %s at %s", weapon, at)
+	}
+	var results []string
+	var cells []XY
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			c, was := after.Cells[y][x], before.Cells[y][x]
+			if c == was {
+				continue
+			}
+			var r string
+			switch {
+			case c == CellMiss:
+				r = g.tr("miss")
+			case c == CellFire || c == CellSunk && was != CellFire:
+				r = g.tr("hit")
+			case c == CellBlast:
+				r = g.tr("mine")
+			default:
+				continue
+			}
+			results = append(results, r)
+			cells = append(cells, XY{x, y})
+		}
+	}
+	if len(cells) > 1 || len(cells) == 1 && cells[0] != xy {
+		// Only the single cell shot at goes without the name.
+		for i, c := range cells {
+			results[i] = fmt.Sprintf("%s %s", g.Lang.cell(c), results[i])
+		}
+	}
+	for _, s := range g.Boards[side.opponent()].Fleet {
+		if xy := s.Cells[0]; s.sunk() && before.Cells[xy.Y][xy.X] != CellSunk {
+			results = append(results, g.tr("sunk a %d-ship", len(s.Cells)))
+		}
+	}
+	if len(results) == 0 {
+		return what
+	}
+	return fmt.Sprintf("%s: %s", what, strings.Join(results, ", "))
+}
+
+// blastText describes the damage done by the mine to own ship of the side, if any,
+// like "the mine has damaged your ship at C5".
+func (g *Game) blastText(side Side, before, after *View) string {
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if before.Cells[y][x] != CellShip || after.Cells[y][x] == CellShip {
+				continue
+			}
+			xy := XY{x, y}
+			switch s := g.Boards[side].shipAt(xy); {
+			case !s.sunk() && side == SideSelf:
+				return g.tr("the mine has damaged your ship at %s", g.Lang.cell(xy))
+			case !s.sunk():
+				return g.tr("the mine has damaged the peer ship at %s", g.Lang.cell(xy))
+			case side == SideSelf:
+				return g.tr("the mine has sunk your %d-ship", len(s.Cells))
+			default:
+				return g.tr("the mine has sunk a %d-ship of the peer", len(s.Cells))
+			}
+		}
+	}
+	return ""
+}
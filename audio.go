@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+
+	"github.com/bukind/seabattle2/sounds"
+)
+
+const (
+	audioSampleRate = 44100
+	maxVolume       = 10
+)
+
+// Sound is the sound effect of the game.
+type Sound int
+
+const (
+	SoundMiss Sound = iota
+	SoundHit
+	SoundSink
+	SoundVictory
+	SoundDefeat
+	numSounds
+)
+
+var soundFiles = [numSounds][]byte{
+	SoundMiss:    sounds.Miss,
+	SoundHit:     sounds.Hit,
+	SoundSink:    sounds.Sink,
+	SoundVictory: sounds.Victory,
+	SoundDefeat:  sounds.Defeat,
+}
+
+// Audio plays the sounds and the music in the window.
+type Audio struct {
+	ctx     *audio.Context
+	sounds  [numSounds][]byte // decoded.
+	music   *audio.Player
+	playing []*audio.Player
+	queue   []queuedSound
+}
+
+// queuedSound is the sound to play at the tick, like when the projectile lands.
+type queuedSound struct {
+	PCM []byte // 16-bit stereo samples.
+	At  int64
+}
+
+// NewAudio decodes the embedded sounds.
+func NewAudio() (*Audio, error) {
+	a := &Audio{ctx: audio.NewContext(audioSampleRate)}
+	for s, b := range soundFiles {
+		stream, err := wav.DecodeWithSampleRate(audioSampleRate, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		if a.sounds[s], err = io.ReadAll(stream); err != nil {
+			return nil, err
+		}
+	}
+	stream, err := wav.DecodeWithSampleRate(audioSampleRate, bytes.NewReader(sounds.Music))
+	if err != nil {
+		return nil, err
+	}
+	if a.music, err = a.ctx.NewPlayer(audio.NewInfiniteLoop(stream, stream.Length())); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// shotSound returns the sound of the shot that has changed the board, if any.
+func shotSound(before, after *View) (Sound, bool) {
+	found := map[Cell]bool{}
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if c := after.Cells[y][x]; c != before.Cells[y][x] {
+				found[c] = true
+			}
+		}
+	}
+	switch {
+	case found[CellSunk]:
+		return SoundSink, true
+	case found[CellFire] || found[CellBlast]:
+		return SoundHit, true
+	case found[CellMiss]:
+		return SoundMiss, true
+	}
+	return 0, false
+}
+
+// playSound plays the sound at the tick, if the game has the audio.
+// The pan moves the sound from the middle, see cuePan.
+func (g *Game) playSound(s Sound, at int64, pan float64) {
+	if g.Audio == nil {
+		return
+	}
+	pcm := g.Audio.sounds[s]
+	if pan != 0 {
+		pcm = panned(pcm, pan)
+	}
+	g.Audio.queue = append(g.Audio.queue, queuedSound{pcm, at})
+}
+
+// playCue plays the short tone telling where the cell is, if the audio cues are on:
+// it comes from the column of the cell, and the higher the row, the higher the tone.
+func (g *Game) playCue(xy XY) {
+	if g.Audio != nil && g.Settings.Cues {
+		g.Audio.queue = append(g.Audio.queue, queuedSound{cueTone(xy), g.Tick})
+	}
+}
+
+// cuePan returns the stereo position of the column, from -1 for the left to 1 for the right.
+func cuePan(xy XY) float64 {
+	return float64(xy.X)/(Ncells-1)*2 - 1
+}
+
+// cueTone returns the tone of the cell, a tenth of a second long.
+func cueTone(xy XY) []byte {
+	const n = audioSampleRate / 10
+	freq := 262 * math.Pow(2, float64(Ncells-1-xy.Y)/4)
+	pcm := make([]byte, n*4)
+	for i := 0; i < n; i++ {
+		// It fades in and out not to click.
+		env := math.Sin(math.Pi * float64(i) / n)
+		v := int16(0x2000 * env * math.Sin(2*math.Pi*freq*float64(i)/audioSampleRate))
+		for ch := 0; ch < 2; ch++ {
+			binary.LittleEndian.PutUint16(pcm[i*4+ch*2:], uint16(v))
+		}
+	}
+	return panned(pcm, cuePan(xy))
+}
+
+// panned returns the 16-bit stereo samples moved to the left or to the right.
+func panned(pcm []byte, pan float64) []byte {
+	gains := [2]float64{min(1, 1-pan), min(1, 1+pan)}
+	out := make([]byte, len(pcm))
+	for i := 0; i+4 <= len(pcm); i += 4 {
+		for ch, gain := range gains {
+			v := int16(binary.LittleEndian.Uint16(pcm[i+ch*2:]))
+			binary.LittleEndian.PutUint16(out[i+ch*2:], uint16(int16(float64(v)*gain)))
+		}
+	}
+	return out
+}
+
+// playEnding plays the sound of the victory or the defeat after the last shot is seen.
+func (g *Game) playEnding() {
+	switch {
+	case g.Boards[SidePeer].Lives == 0:
+		g.playSound(SoundVictory, g.effectsEnd(), 0)
+	case g.Boards[SideSelf].Lives == 0:
+		g.playSound(SoundDefeat, g.effectsEnd(), 0)
+	}
+}
+
+// updateAudio plays the sounds due and the music with the volumes of the settings.
+// In browsers the audio starts only after the player has clicked or pressed a key,
+// and the sounds due before are dropped rather than played late.
+func (g *Game) updateAudio() {
+	a := g.Audio
+	if a == nil {
+		return
+	}
+	due := func(q queuedSound) bool { return q.At <= g.Tick }
+	if !a.ctx.IsReady() {
+		a.queue = slices.DeleteFunc(a.queue, due)
+		return
+	}
+	s := g.Settings
+	master := float64(s.Volume) / maxVolume
+	music := master * float64(s.MusicVolume) / maxVolume
+	a.music.SetVolume(music)
+	if music > 0 && !a.music.IsPlaying() {
+		a.music.Play()
+	} else if music == 0 && a.music.IsPlaying() {
+		a.music.Pause()
+	}
+	a.playing = slices.DeleteFunc(a.playing, func(p *audio.Player) bool { return !p.IsPlaying() })
+	for _, q := range a.queue {
+		if due(q) {
+			p := a.ctx.NewPlayerFromBytes(q.PCM)
+			p.SetVolume(master * float64(s.SoundVolume) / maxVolume)
+			p.Play()
+			a.playing = append(a.playing, p)
+		}
+	}
+	a.queue = slices.DeleteFunc(a.queue, due)
+}
+
+// busy returns whether the sounds are still played or waiting.
+func (a *Audio) busy() bool {
+	return a != nil && (len(a.queue) > 0 || len(a.playing) > 0)
+}
@@ -1,117 +1,176 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"image/color"
 	"log"
-	"math"
-	"math/rand"
-	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+// Board keeps the true state of the board of one side.
+// Players see it through the View.
 type Board struct {
 	Game  *Game
 	Side  Side
 	Lives int
 	Ships []int // number of ships of size = idx+1
 	Cells [][]Cell
+	Scans []Scan // radar scans done on the board.
+	Fleet []*Ship
 }
 
 func NewBoard(g *Game, side Side) *Board {
 	rows := make([][]Cell, Ncells)
-	cell := CellEmpty
-	if side == SidePeer {
-		cell = CellMist
-	}
 	for i := range rows {
-		r := make([]Cell, Ncells)
-		rows[i] = r
-		for j := range r {
-			r[j] = cell
-		}
+		rows[i] = make([]Cell, Ncells)
 	}
-	return &Board{
+	b := &Board{
 		Game:  g,
 		Side:  side,
 		Cells: rows,
 		Ships: make([]int, maxShipSize),
 	}
+	b.clear()
+	return b
+}
+
+// clear removes everything from the board.
+func (b *Board) clear() {
+	for _, r := range b.Cells {
+		for j := range r {
+			r[j] = CellEmpty
+		}
+	}
+	for i := range b.Ships {
+		b.Ships[i] = 0
+	}
+	b.Lives = 0
+	b.Scans = nil
+	b.Fleet = nil
+}
+
+// errNoRoom is returned when the fleet does not fit on the board.
+var errNoRoom = errors.New("no room for the fleet")
+
+// shipsLeft returns the number of the ships of the board not sunk yet.
+func (b *Board) shipsLeft() int {
+	n := 0
+	for _, k := range b.Ships {
+		n += k
+	}
+	return n
+}
+
+// fleetSize returns the number of ships of the size in the fleet.
+func fleetSize(size int) int {
+	return maxShipSize + 1 - size
+}
+
+// Shipyard lets the strategy place its fleet on own board,
+// without reaching the rest of the game through the board.
+type Shipyard struct {
+	board *Board
+}
+
+// View returns own board with the islands and the ships placed so far.
+func (y Shipyard) View() *View {
+	return y.board.view(y.board.Side)
+}
+
+// PutShip puts the ship from p0 to p1, see Board.putShip.
+func (y Shipyard) PutShip(p0, p1 XY) error {
+	return y.board.putShip(p0, p1)
+}
+
+// AddRandomShips places the rest of the fleet at random.
+func (y Shipyard) AddRandomShips() error {
+	return y.board.addRandomShips(30)
+}
+
+// PutMine hides the mine at the cell, see Board.putMine.
+func (y Shipyard) PutMine(xy XY) error {
+	return y.board.putMine(xy)
+}
+
+// setup places islands, ships and mines on the board.
+// The ships and the mines are placed by the place function.
+// The mines it has not hidden are hidden at random, except those the player hides later.
+func (b *Board) setup(retries int, place func(y Shipyard) error) error {
+	var err error
+	for attempt := 0; attempt < retries; attempt++ {
+		b.clear()
+		b.placeIslands(b.Game.Rules.Islands)
+		if err = place(Shipyard{b}); err == nil {
+			err = b.completeFleet()
+		}
+		if err == nil {
+			if b.Side != SideSelf || !b.Game.PlacingMines {
+				b.placeMines(b.Game.Rules.Mines - b.mines())
+			}
+			return nil
+		}
+		if !errors.Is(err, errNoRoom) {
+			return err
+		}
+	}
+	return err
+}
+
+// completeFleet checks that the whole fleet is placed.
+func (b *Board) completeFleet() error {
+	for s := 1; s <= maxShipSize; s++ {
+		if b.Ships[s-1] != fleetSize(s) {
+			return fmt.Errorf("%d ships of size %d placed, want %d", b.Ships[s-1], s, fleetSize(s))
+		}
+	}
+	// Replace working cells back to empty.
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if b.Cells[y][x] == CellOily {
+				b.Cells[y][x] = CellEmpty
+			}
+		}
+	}
+	return nil
 }
 
-func (b *Board) draw(screen *ebiten.Image) {
+// draw draws the board as seen in the view.
+func (b *Board) draw(screen *ebiten.Image, v *View) {
 	g := b.Game
 	// Draw cells
 	for x := 0; x < Ncells; x++ {
 		for y := 0; y < Ncells; y++ {
 			g.opts.GeoM.Reset()
 			g.moveXY(&g.opts.GeoM, x, y, b.Side)
-			b.drawCellInto(x, y, g.cellImage)
+			c := v.Cells[y][x]
+			if before, ok := g.pendingCell(b.Side, XY{x, y}); ok {
+				c = before
+			}
+			g.Theme.drawCellInto(c, g.cellImage)
 			screen.DrawImage(g.cellImage, &g.opts)
 		}
-		text.Draw(screen, fmt.Sprintf("%c", 'A'+x), &text.GoTextFace{
-			Source: ptSansFontSource,
-			Size:   cellSize * 0.8,
-		}, g.textInXY(x, Ncells, b.Side))
-	}
-}
-
-func (b *Board) drawCellInto(x, y int, into *ebiten.Image) {
-	g := b.Game
-	c := b.Cells[y][x]
-	params := cellParams[c]
-	if params.SceneColor != colorEmpty {
-		into.Fill(params.SceneColor)
-	} else {
-		into.Fill(color.RGBA{0, 0, 0, 0})
-	}
-	if params.CircleColor != colorEmpty {
-		// Draw a circle in the middle of the cell.
-		var path vector.Path
-		path.Arc(cellSizeF/2, cellSizeF/2, cellSize*params.CircleRadius, 0, math.Pi*2, vector.Clockwise)
-		path.Close()
-		g.vtx, g.idx = path.AppendVerticesAndIndicesForFilling(g.vtx[:0], g.idx[:0])
-		for i := range g.vtx {
-			setVtxColor(&g.vtx[i], params.CircleColor)
+		if !g.HideCoords {
+			text.Draw(screen, string(g.Lang.columns[x]), g.Theme.face(cellSize*0.8), g.textInXY(x, Ncells, b.Side))
 		}
-		op := &ebiten.DrawTrianglesOptions{
-			AntiAlias: true,
-			FillRule:  ebiten.FillRuleNonZero,
-		}
-		into.DrawTriangles(g.vtx, g.idx, fillImage, op)
 	}
+	b.drawShips(screen, v)
+	b.drawScans(screen, v)
 }
 
+// addRandomShips places the rest of the fleet at random.
 func (b *Board) addRandomShips(retries int) error {
-	num := 1
 	for s := maxShipSize; s > 0; s-- {
-		for n := 0; n < num; n++ {
+		for b.Ships[s-1] < fleetSize(s) {
 			placed := false
 			for attempt := 0; attempt < retries; attempt++ {
 				if placed = b.placeShip(s); placed {
-					b.Lives += s
 					break
 				}
 			}
 			if !placed {
-				return fmt.Errorf("cannot place ship of size %d", s)
-			}
-			b.Ships[s-1]++
-		}
-		num++
-	}
-	// Replace working cells back to empty.
-	cell := CellEmpty
-	if b.Side == SidePeer {
-		cell = CellMist
-	}
-	for y := 0; y < Ncells; y++ {
-		for x := 0; x < Ncells; x++ {
-			if b.Cells[y][x] == CellOily {
-				b.Cells[y][x] = cell
+				return fmt.Errorf("%w: cannot place ship of size %d", errNoRoom, s)
 			}
 		}
 	}
@@ -156,87 +215,65 @@ func seqXY(rof func(func(XY) bool)) []XY {
 func (b *Board) placeShip(size int) bool {
 	xSize := 0
 	ySize := size - 1
-	if r := rand.Intn(2); r != 0 {
+	if r := b.Game.Rand.Intn(2); r != 0 {
 		xSize = size - 1
 		ySize = 0
 	}
-	p0 := XY{rand.Intn(Ncells - xSize), rand.Intn(Ncells - ySize)}
+	p0 := XY{b.Game.Rand.Intn(Ncells - xSize), b.Game.Rand.Intn(Ncells - ySize)}
 	p1 := XY{p0.X + xSize, p0.Y + ySize}
-	for _, xy := range seqXY(shipSeq(p0, p1)) {
-		switch c := b.Cells[xy.Y][xy.X]; c {
-		case CellEmpty, CellMist:
-			// ok
-		default:
-			return false
-		}
-	}
-	cell := CellShip
-	if b.Side == SidePeer {
-		cell = CellHide
+	return b.putShip(p0, p1) == nil
+}
+
+// putShip puts the ship from p0 to p1 on the board.
+// The ship must be straight, must fit into the fleet, and must not touch other ships by sides.
+func (b *Board) putShip(p0, p1 XY) error {
+	if !inBoard(p0) || !inBoard(p1) || (p0.X != p1.X && p0.Y != p1.Y) {
+		return fmt.Errorf("bad ship %s-%s", p0, p1)
 	}
-	for _, xy := range seqXY(shipSeq(p0, p1)) {
-		b.Cells[xy.Y][xy.X] = cell
+	cells := seqXY(shipSeq(p0, p1))
+	size := len(cells)
+	if size > maxShipSize || b.Ships[size-1] >= fleetSize(size) {
+		return fmt.Errorf("no more ships of size %d in the fleet", size)
 	}
-	for _, xy := range seqXY(b.markAround(p0, p1)) {
-		switch c := b.Cells[xy.Y][xy.X]; c {
-		case CellEmpty, CellMist:
-			b.Cells[xy.Y][xy.X] = CellOily
+	for _, xy := range cells {
+		if b.Cells[xy.Y][xy.X] != CellEmpty {
+			return fmt.Errorf("ship %s-%s touches another ship or an island", p0, p1)
 		}
 	}
-	return true
-}
-
-func (b *Board) markAround(p0, p1 XY) func(func(XY) bool) {
-	return func(yield func(xy XY) bool) {
-		seqs := make([]func(func(XY) bool), 0, 4)
-		// log.Printf("markAround for %s %s", p0, p1)
-		for _, y := range []int{p0.Y - 1, p1.Y + 1} {
-			if y >= 0 && y < Ncells {
-				// log.Printf(" shipSeq %s %s", XY{p0.X, y}, XY{p1.X, y})
-				seqs = append(seqs, shipSeq(XY{p0.X, y}, XY{p1.X, y}))
-			}
-		}
-		for _, x := range []int{p0.X - 1, p1.X + 1} {
-			if x >= 0 && x < Ncells {
-				// log.Printf(" shipSeq %s %s", XY{x, p0.Y}, XY{x, p1.Y})
-				seqs = append(seqs, shipSeq(XY{x, p0.Y}, XY{x, p1.Y}))
-			}
-		}
-		for _, seq := range seqs {
-			for _, xy := range seqXY(seq) {
-				if !yield(xy) {
-					return
-				}
-			}
+	s := NewShip(len(b.Fleet)+1, p0, p1)
+	for _, xy := range s.Cells {
+		b.Cells[xy.Y][xy.X] = CellShip
+	}
+	b.Fleet = append(b.Fleet, s)
+	b.Ships[size-1]++
+	b.Lives += size
+	for _, xy := range s.around() {
+		if b.Cells[xy.Y][xy.X] == CellEmpty {
+			b.Cells[xy.Y][xy.X] = CellOily
 		}
 	}
+	return nil
 }
 
 // return true if you can hit again.
 func (b *Board) hitCell(xy XY) bool {
 	switch c := b.Cells[xy.Y][xy.X]; c {
-	case CellEmpty, CellMist:
+	case CellEmpty:
 		b.Cells[xy.Y][xy.X] = CellMiss
 		return false
-	case CellHide, CellShip:
-		b.Cells[xy.Y][xy.X] = CellFire
-		b.Lives--
-		sunk := b.isShipSunk(xy.X, xy.Y)
-		if len(sunk) > 0 {
-			log.Printf("sunk %v", sunk)
-			b.Ships[len(sunk)-1]--
-			for _, xy := range sunk {
-				b.Cells[xy.Y][xy.X] = CellSunk
-			}
-			if b.Side == SideSelf {
-				for _, xy := range seqXY(b.markAround(sunk[0], sunk[len(sunk)-1])) {
-					if c := b.Cells[xy.Y][xy.X]; c == CellEmpty {
-						b.Cells[xy.Y][xy.X] = CellOily
-					}
-				}
-			}
+	case CellMine:
+		b.Cells[xy.Y][xy.X] = CellBlast
+		b.Game.mineBlast(b.Side.opponent())
+		return false
+	case CellShip:
+		if s := b.damage(xy); s != nil {
 			g := b.Game
-			g.Message = fmt.Sprintf("Sunk, remaining: %v, %v", g.Boards[0].Ships, g.Boards[1].Ships)
+			left := b.shipsLeft()
+			if b.Side == SidePeer {
+				g.Message = g.trn(left, "You have sunk a %d-ship, %d ship left", "You have sunk a %d-ship, %d ships left", len(s.Cells), left)
+			} else {
+				g.Message = g.trn(left, "The peer has sunk your %d-ship, %d ship left", "The peer has sunk your %d-ship, %d ships left", len(s.Cells), left)
+			}
 		}
 		return true
 	}
@@ -245,42 +282,19 @@ func (b *Board) hitCell(xy XY) bool {
 	return true
 }
 
-// the ship is sunk when result is not empty, and it will contains all its cells.
-func (b *Board) isShipSunk(x0, y0 int) []XY {
-	result := make([]XY, 0, 4)
-	result = append(result, XY{x0, y0})
-	for dx := -1; dx < 2; dx += 2 {
-		for x := x0 + dx; x >= 0 && x < Ncells; x += dx {
-			switch c := b.Cells[y0][x]; c {
-			case CellShip, CellHide:
-				return nil
-			case CellFire:
-				result = append(result, XY{x, y0})
-			default:
-				x = -10000
-			}
-		}
+// damage hits the ship cell, and returns the ship if it is sunk by the hit.
+func (b *Board) damage(xy XY) *Ship {
+	b.Cells[xy.Y][xy.X] = CellFire
+	b.Lives--
+	s := b.shipAt(xy)
+	s.hit(xy, b.Game.Tick)
+	if !s.sunk() {
+		return nil
 	}
-	for dy := -1; dy < 2; dy += 2 {
-		for y := y0 + dy; y >= 0 && y < Ncells; y += dy {
-			switch c := b.Cells[y][x0]; c {
-			case CellShip, CellHide:
-				return nil
-			case CellFire:
-				result = append(result, XY{x0, y})
-			default:
-				y = -10000
-			}
-		}
+	log.Printf("sunk ship #%d %v, hit at ticks %v", s.ID, s.Cells, s.HitAt)
+	b.Ships[len(s.Cells)-1]--
+	for _, xy := range s.Cells {
+		b.Cells[xy.Y][xy.X] = CellSunk
 	}
-	slices.SortFunc(result, func(a, b XY) int {
-		if s := sign(a.Y - b.Y); s != 0 {
-			return s
-		}
-		if s := sign(a.X - b.X); s != 0 {
-			return s
-		}
-		return 0
-	})
-	return result
+	return s
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testGame returns the game with the boards drawn by the rows of the glyphs like
+// cellGlyphs: '#' are the ships, '+' the mines and '^' the islands. The ships go
+// along the rows or down the columns, and their sizes need not match the fleet.
+func testGame(t *testing.T, rules Rules, self, peer []string) *Game {
+	t.Helper()
+	g := NewGame(rules, nil)
+	g.Rand = rand.New(rand.NewSource(1))
+	for side, rows := range [2][]string{self, peer} {
+		b := g.Boards[side]
+		if len(rows) != Ncells {
+			t.Fatalf("board %d has %d rows, want %d", side, len(rows), Ncells)
+		}
+		for y, row := range rows {
+			for x := 0; x < Ncells; x++ {
+				switch row[x] {
+				case '+':
+					b.Cells[y][x] = CellMine
+				case '^':
+					b.Cells[y][x] = CellRock
+				}
+			}
+		}
+		glyph := func(x, y int) byte {
+			if x >= Ncells || y >= Ncells {
+				return '.'
+			}
+			return rows[y][x]
+		}
+		for y := 0; y < Ncells; y++ {
+			for x := 0; x < Ncells; x++ {
+				if glyph(x, y) != '#' || b.Cells[y][x] == CellShip {
+					continue
+				}
+				end := XY{x, y}
+				for glyph(end.X+1, end.Y) == '#' {
+					end.X++
+				}
+				for end.X == x && glyph(end.X, end.Y+1) == '#' {
+					end.Y++
+				}
+				s := NewShip(len(b.Fleet)+1, XY{x, y}, end)
+				for _, xy := range s.Cells {
+					b.Cells[xy.Y][xy.X] = CellShip
+				}
+				b.Fleet = append(b.Fleet, s)
+				b.Ships[len(s.Cells)-1]++
+				b.Lives += len(s.Cells)
+			}
+		}
+	}
+	return g
+}
+
+// emptyBoard is the board without anything.
+var emptyBoard = []string{
+	"........",
+	"........",
+	"........",
+	"........",
+	"........",
+	"........",
+	"........",
+	"........",
+}
+
+// rows returns the board drawn with the glyphs, like testGame takes it.
+func rows(cells [][]Cell) []string {
+	var rows []string
+	for _, row := range cells {
+		var s []byte
+		for _, c := range row {
+			s = append(s, cellGlyphs[c])
+		}
+		rows = append(rows, string(s))
+	}
+	return rows
+}
+
+func TestHitCellSinks(t *testing.T) {
+	g := testGame(t, Rules{}, emptyBoard, []string{
+		"##......",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		".......#",
+	})
+	b := g.Boards[SidePeer]
+	if !b.hitCell(XY{0, 0}) || b.Cells[0][0] != CellFire {
+		t.Fatalf("the first hit: got %v", rows(b.Cells)[0])
+	}
+	if !b.hitCell(XY{1, 0}) || b.Cells[0][0] != CellSunk || b.Cells[0][1] != CellSunk {
+		t.Fatalf("the second hit: got %v", rows(b.Cells)[0])
+	}
+	if b.Ships[1] != 0 || b.shipsLeft() != 1 || b.Lives != 1 {
+		t.Errorf("after sinking: ships %v, lives %d", b.Ships, b.Lives)
+	}
+	if b.hitCell(XY{2, 0}) || b.Cells[0][2] != CellMiss {
+		t.Errorf("the miss: got %v", rows(b.Cells)[0])
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BotStrategy plays the peer by an external program.
+// The program talks the line protocol described in PROTOCOL.md over its stdin and stdout.
+type BotStrategy struct {
+	Name    string
+	Rules   Rules
+	Timeout time.Duration // how long to wait for every reply.
+
+	cmd    *exec.Cmd
+	mu     sync.Mutex // guards in, as the chat is said while the game talks to the bot.
+	in     io.WriteCloser
+	out    io.ReadCloser
+	lines  chan string
+	chat   chan string   // the chat lines, which may come at any time.
+	done   chan struct{} // closed when nobody reads the lines any more.
+	waited chan error
+	views  [2]*View // the last reported own board and the opponent board.
+	begun  bool     // the islands of the opponent and own mines are sent.
+	ended  bool
+}
+
+// botCells are the names of the cells reported to the bot.
+var botCells = map[Cell]string{
+	CellMiss:  "miss",
+	CellFire:  "hit",
+	CellSunk:  "sunk",
+	CellBlast: "mine",
+}
+
+// NewBotStrategy starts the bot command and greets it.
+func NewBotStrategy(command string, rules Rules, timeout time.Duration) (*BotStrategy, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty bot command")
+	}
+	s := &BotStrategy{
+		Name:    args[0],
+		Rules:   rules,
+		Timeout: timeout,
+		cmd:     exec.Command(args[0], args[1:]...),
+		lines:   make(chan string, 16),
+		chat:    make(chan string, 16),
+		done:    make(chan struct{}),
+		waited:  make(chan error, 1),
+	}
+	// Let the bot log its debug output.
+	s.cmd.Stderr = log.Writer()
+	var err error
+	if s.in, err = s.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if s.out, err = s.cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start bot: %w", err)
+	}
+	go func() {
+		scanner := bufio.NewScanner(s.out)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if text, ok := strings.CutPrefix(strings.TrimSpace(line), "chat"); ok && (text == "" || text[0] == ' ') {
+				if text = strings.TrimSpace(text); text != "" {
+					select {
+					case s.chat <- text:
+					default:
+						// Nobody listens, like in the tournament.
+					}
+				}
+				continue
+			}
+			select {
+			case s.lines <- line:
+			case <-s.done:
+				// The lines after the end of the game are dropped.
+			}
+		}
+		close(s.lines)
+		close(s.chat)
+		s.waited <- s.cmd.Wait()
+	}()
+
+	s.send("seabattle 1")
+	reply, err := s.read()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	name, ok := strings.CutPrefix(reply, "ready")
+	if !ok {
+		return nil, s.fail(fmt.Errorf("got %q, want ready", reply))
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		s.Name = name
+	}
+	s.send("rules " + botRules(rules))
+	return s, nil
+}
+
+// botRules returns the rules as the space separated key=value pairs.
+func botRules(r Rules) string {
+	var fleet []string
+	for size := maxShipSize; size > 0; size-- {
+		for n := 0; n < fleetSize(size); n++ {
+			fleet = append(fleet, fmt.Sprint(size))
+		}
+	}
+	return fmt.Sprintf("size=%d fleet=%s weapons=%s mines=%d mine-damage=%s islands=%d moving=%s",
+		Ncells, strings.Join(fleet, ","), onOff(r.Weapons), r.Mines, onOff(r.MineDamage), r.Islands, onOff(r.Moving))
+}
+
+// send writes the line to the bot.
+// Write errors are ignored, as the bot failure is noticed when reading its reply.
+func (s *BotStrategy) send(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log.Printf("bot < %s", line)
+	fmt.Fprintln(s.in, line)
+}
+
+// closeIn closes the input of the bot, so that it exits.
+func (s *BotStrategy) closeIn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.in.Close()
+}
+
+// read returns the next meaningful line from the bot.
+// Empty lines are skipped, and the lines starting with "log" go to the log.
+func (s *BotStrategy) read() (string, error) {
+	timeout := time.After(s.Timeout)
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				return "", fmt.Errorf("bot %s has exited", s.Name)
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if msg, ok := strings.CutPrefix(line, "log"); ok && (msg == "" || msg[0] == ' ') {
+				log.Printf("bot %s: %s", s.Name, strings.TrimSpace(msg))
+				continue
+			}
+			log.Printf("bot > %s", line)
+			return line, nil
+		case <-timeout:
+			return "", fmt.Errorf("bot %s has not replied in %v", s.Name, s.Timeout)
+		}
+	}
+}
+
+// Say sends the chat message of the player to the bot, see ChatLink.
+func (s *BotStrategy) Say(text string) error {
+	line := "chat " + strings.Join(strings.Fields(text), " ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log.Printf("bot < %s", line)
+	if _, err := fmt.Fprintln(s.in, line); err != nil {
+		return fmt.Errorf("bot %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Heard returns the chat messages of the bot, see ChatLink.
+func (s *BotStrategy) Heard() <-chan string {
+	return s.chat
+}
+
+// fail stops the misbehaving bot and returns the error.
+// Its output is closed, so that the reader exits even if the bot has left children behind.
+func (s *BotStrategy) fail(err error) error {
+	if !s.ended {
+		s.ended = true
+		close(s.done)
+		s.closeIn()
+		s.cmd.Process.Kill()
+		s.out.Close()
+	}
+	return fmt.Errorf("bot %s: %w", s.Name, err)
+}
+
+func (s *BotStrategy) Place(yard Shipyard) error {
+	own := yard.View()
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if own.Cells[y][x] == CellRock {
+				s.send(fmt.Sprintf("island %s", XY{x, y}))
+			}
+		}
+	}
+	s.send("place")
+	for {
+		line, err := s.read()
+		if err != nil {
+			return s.fail(err)
+		}
+		args := strings.Fields(strings.ToLower(line))
+		switch {
+		case len(args) == 1 && args[0] == "done":
+			s.views[0] = yard.View()
+			return nil
+		case len(args) == 1 && args[0] == "random":
+			if err := yard.AddRandomShips(); err != nil {
+				return err
+			}
+			s.views[0] = yard.View()
+			s.send("fleet " + shipList(s.views[0].Fleet))
+			return nil
+		case len(args) == 3 && args[0] == "ship":
+			p0, err0 := parseXY(args[1])
+			p1, err1 := parseXY(args[2])
+			if err0 != nil || err1 != nil {
+				return s.fail(fmt.Errorf("bad ship %q", line))
+			}
+			if err := yard.PutShip(p0, p1); err != nil {
+				return s.fail(err)
+			}
+		case len(args) == 2 && args[0] == "mine":
+			xy, err := parseXY(args[1])
+			if err != nil {
+				return s.fail(fmt.Errorf("bad mine %q", line))
+			}
+			if err := yard.PutMine(xy); err != nil {
+				return s.fail(err)
+			}
+		default:
+			return s.fail(fmt.Errorf("got %q, want ship, mine, done or random", line))
+		}
+	}
+}
+
+// shipList returns the ships of the fleet like "A1-A4 C3-D3 F8-F8".
+func shipList(fleet []*Ship) string {
+	var ships []string
+	for _, s := range fleet {
+		ships = append(ships, fmt.Sprintf("%s-%s", s.Cells[0], s.Cells[len(s.Cells)-1]))
+	}
+	return strings.Join(ships, " ")
+}
+
+// begin sends what the bot knows before the first turn: the islands of the opponent,
+// and own mines, including those hidden at random after the placement.
+func (s *BotStrategy) begin(own, theirs *View) {
+	if s.begun {
+		return
+	}
+	s.begun = true
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if theirs.Cells[y][x] == CellRock {
+				s.send(fmt.Sprintf("enemy %s island", XY{x, y}))
+			}
+		}
+	}
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if own.Cells[y][x] == CellMine {
+				s.send(fmt.Sprintf("own %s mine", XY{x, y}))
+			}
+		}
+	}
+}
+
+func (s *BotStrategy) Act(own, v *View, a *Arsenal, canMove func(at, dir XY) bool) (Action, error) {
+	s.begin(own, v)
+	turn := "turn"
+	if s.Rules.Weapons {
+		for w := Weapon(0); w < numWeapons; w++ {
+			if a.ready(w) {
+				turn += " " + strings.ToLower(w.String())
+			}
+		}
+	}
+	s.send(turn)
+	line, err := s.read()
+	if err != nil {
+		return Action{}, s.fail(err)
+	}
+	args := strings.Fields(strings.ToLower(line))
+	switch {
+	case len(args) == 2 && args[0] == "fire":
+		xy, err := parseXY(args[1])
+		if err != nil {
+			return Action{}, s.fail(err)
+		}
+		return Action{Weapon: WeaponShot, Target: xy}, nil
+	case len(args) == 3 && args[0] == "move":
+		xy, err := parseXY(args[1])
+		d, ok := cliDirections[args[2]]
+		if err != nil || !ok {
+			return Action{}, s.fail(fmt.Errorf("bad move %q", line))
+		}
+		return Action{Move: true, Target: xy, Dir: d}, nil
+	case len(args) == 2:
+		w, ok := parseWeapon(args[0])
+		xy, err := parseXY(args[1])
+		if !ok || err != nil {
+			return Action{}, s.fail(fmt.Errorf("bad action %q", line))
+		}
+		return Action{Weapon: w, Target: xy}, nil
+	}
+	return Action{}, s.fail(fmt.Errorf("got %q, want fire, a weapon or move", line))
+}
+
+func (s *BotStrategy) Observe(own bool, act Action, mine, theirs *View) {
+	if s.ended {
+		return
+	}
+	s.begin(mine, theirs)
+	if !own && act.Move {
+		s.send("enemy moved")
+	}
+	// The board of the shooter changes only by the blast of the mine it has hit.
+	enemy, ours := "enemy", "own"
+	if !act.Move && own {
+		ours = "own blast"
+	} else if !act.Move {
+		enemy = "enemy blast"
+	}
+	s.report(enemy, s.views[1], theirs)
+	s.report(ours, s.views[0], mine)
+	s.views = [2]*View{mine, theirs}
+}
+
+// report sends the changes of the board since the last report.
+func (s *BotStrategy) report(board string, before, after *View) {
+	scans := 0
+	if before != nil {
+		scans = len(before.Scans)
+	}
+	for _, sc := range after.Scans[min(scans, len(after.Scans)):] {
+		found := "clear"
+		if sc.Found {
+			found = "contact"
+		}
+		s.send(fmt.Sprintf("%s radar %s %s", board, sc.Center, found))
+	}
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			c := after.Cells[y][x]
+			if before != nil && before.Cells[y][x] == c {
+				continue
+			}
+			if name, ok := botCells[c]; ok {
+				s.send(fmt.Sprintf("%s %s %s", board, XY{x, y}, name))
+			}
+		}
+	}
+}
+
+func (s *BotStrategy) End(outcome string) error {
+	if s.ended {
+		return nil
+	}
+	s.ended = true
+	close(s.done)
+	s.send("gameover " + outcome)
+	s.send("quit")
+	s.closeIn()
+	select {
+	case err := <-s.waited:
+		return err
+	case <-time.After(s.Timeout):
+		s.cmd.Process.Kill()
+		return fmt.Errorf("bot %s has not quit in %v", s.Name, s.Timeout)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowBot places the fleet at random, and never replies to its turn.
+const slowBot = `read greeting; echo ready slow
+while read line; do
+	case "$line" in
+	place) echo random ;;
+	turn*) yes log thinking ;;
+	quit) exit 0 ;;
+	esac
+done`
+
+func startBot(t *testing.T, script string, timeout time.Duration) *BotStrategy {
+	t.Helper()
+	if runtime.GOOS == "js" {
+		t.Skip("no processes in the browser")
+	}
+	path := filepath.Join(t.TempDir(), "bot.sh")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The bot floods the log.
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	s, err := NewBotStrategy("sh "+path, Rules{}, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBotPeerThinksInBackground(t *testing.T) {
+	g := NewGame(Rules{}, startBot(t, slowBot, 200*time.Millisecond))
+	if err := g.init(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := g.peerToHit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.updatePeer(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("the game has waited for the bot for %v", d)
+	}
+	if g.Thinking == nil {
+		t.Fatal("the peer is not thinking")
+	}
+	r := <-g.Thinking
+	if r.Err == nil || !strings.Contains(r.Err.Error(), "has not replied") {
+		t.Errorf("got %v, want the timeout", r.Err)
+	}
+}
+
+func TestBotFailStopsReader(t *testing.T) {
+	s := startBot(t, slowBot, 100*time.Millisecond)
+	own := NewGame(Rules{}, nil).Boards[SidePeer]
+	if err := own.setup(30, s.Place); err != nil {
+		t.Fatal(err)
+	}
+	// The bot floods its output without replying, and is failed by the timeout.
+	if _, err := s.Act(own.view(SidePeer), own.view(SideSelf), NewArsenal(), own.canMoveAt); err == nil {
+		t.Fatal("got the action from the silent bot")
+	}
+	select {
+	case <-s.waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the reader of the failed bot has not exited")
+	}
+	if err := s.End("stopped"); err != nil {
+		t.Errorf("End after the failure: %v", err)
+	}
+}
+
+// mineBot hides a mine at A1 before placing the fleet at random.
+const mineBot = `read greeting; echo ready miner
+while read line; do
+	case "$line" in
+	place) echo mine A1; echo random ;;
+	quit) exit 0 ;;
+	esac
+done`
+
+func TestBotPlacesMines(t *testing.T) {
+	s := startBot(t, mineBot, time.Second)
+	b := NewGame(Rules{Mines: 2}, nil).Boards[SidePeer]
+	if err := b.setup(30, s.Place); err != nil {
+		t.Fatal(err)
+	}
+	// The other mine is hidden at random.
+	if b.Cells[0][0] != CellMine || b.mines() != 2 {
+		t.Errorf("got %q, want the mine at A1 and one more", rows(b.Cells))
+	}
+	if err := s.End("stopped"); err != nil {
+		t.Error(err)
+	}
+}
+
+// chatBot greets the player in the chat, and echoes what it is told.
+const chatBot = `read greeting; echo ready chatty; echo chat hello
+while read line; do
+	case "$line" in
+	place) echo chat placing; echo random ;;
+	chat*) echo "chat echo ${line#chat }" ;;
+	quit) exit 0 ;;
+	esac
+done`
+
+func TestBotChat(t *testing.T) {
+	s := startBot(t, chatBot, time.Second)
+	// The chat does not get in the way of the replies.
+	if err := NewGame(Rules{}, nil).Boards[SidePeer].setup(30, s.Place); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Say("good  luck\n"); err != nil {
+		t.Fatal(err)
+	}
+	var heard []string
+	for len(heard) == 0 || heard[len(heard)-1] != "echo good luck" {
+		select {
+		case got := <-s.Heard():
+			heard = append(heard, got)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("has heard %q, want the echo", heard)
+		}
+	}
+	// The placement may be retried.
+	if len(heard) < 3 || heard[0] != "hello" || heard[1] != "placing" {
+		t.Errorf("has heard %q, want hello, placing and the echo", heard)
+	}
+	if err := s.End("stopped"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := <-s.Heard(); ok {
+		t.Error("the chat is open after the end")
+	}
+}
+
+// lineBuffer collects the lines sent to the bot.
+type lineBuffer struct {
+	strings.Builder
+}
+
+func (b *lineBuffer) Close() error {
+	return nil
+}
+
+func TestBotObserveBlast(t *testing.T) {
+	g := testGame(t, Rules{Mines: 2, MineDamage: true}, minedBoard, minedBoard)
+	var sent lineBuffer
+	// The game has begun, and own mines are known.
+	s := &BotStrategy{in: &sent, begun: true}
+	s.views = [2]*View{g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer)}
+	act := Action{Weapon: WeaponShot, Target: XY{7, 7}}
+	if _, err := g.shoot(SidePeer, act.Weapon, act.Target); err != nil {
+		t.Fatal(err)
+	}
+	s.Observe(true, act, g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer))
+	lines := strings.Split(strings.TrimSpace(sent.String()), "\n")
+	// The own ship is damaged by the mine, not by the opponent.
+	if len(lines) != 2 || lines[0] != "enemy H8 mine" || lines[1] != "own blast C3 hit" && lines[1] != "own blast D3 hit" {
+		t.Errorf("sent %q to the bot", lines)
+	}
+	sent.Reset()
+	if _, err := g.shoot(SideSelf, act.Weapon, act.Target); err != nil {
+		t.Fatal(err)
+	}
+	s.Observe(false, act, g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer))
+	if lines := strings.Split(strings.TrimSpace(sent.String()), "\n"); len(lines) != 2 || lines[0] != "enemy blast C3 hit" && lines[0] != "enemy blast D3 hit" || lines[1] != "own H8 mine" {
+		t.Errorf("sent %q to the bot after the mine of the opponent", lines)
+	}
+}
+
+func TestBotIslandsAndMines(t *testing.T) {
+	g := testGame(t, Rules{Mines: 1, Islands: 1}, viewBoard, viewBoard)
+	var sent lineBuffer
+	s := &BotStrategy{in: &sent, lines: make(chan string, 1), Timeout: time.Second}
+	s.views[0] = g.Boards[SidePeer].view(SidePeer)
+	s.lines <- "fire D3"
+	act, err := s.Act(g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer), NewArsenal(), g.Boards[SidePeer].canMoveAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "enemy D3 island\nown G4 mine\nturn\n"; sent.String() != want {
+		t.Errorf("sent %q before the first turn, want %q", sent.String(), want)
+	}
+	sent.Reset()
+	// The island is shot by the bot, and nothing changes.
+	if _, err := g.shoot(SidePeer, act.Weapon, act.Target); err != nil {
+		t.Fatal(err)
+	}
+	s.Observe(true, act, g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer))
+	act = Action{Weapon: WeaponShot, Target: XY{6, 3}}
+	if _, err := g.shoot(SideSelf, act.Weapon, act.Target); err != nil {
+		t.Fatal(err)
+	}
+	s.Observe(false, act, g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer))
+	if want := "own G4 mine\n"; sent.String() != want {
+		t.Errorf("sent %q after the shots, want %q", sent.String(), want)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	maxChatText  = 200 // runes in the chat message.
+	maxChatName  = 20  // runes in the name of the sender.
+	maxChatLines = 100 // kept by the server for every game.
+	chatRows     = 5   // of the chat history shown in the window.
+	chatLineSize = cellSize * 3 / 5
+)
+
+// quickEmotes are sent by F1-F5 in the window, or by their names in the API.
+// Their texts are sent in English, and shown in the language of the reader.
+var quickEmotes = []struct{ Name, Text string }{
+	{"nice", "Nice shot!"},
+	{"argh", "Argh!"},
+	{"oops", "Oops!"},
+	{"gg", "Good game!"},
+	{"hurry", "Your turn!"},
+}
+
+var emoteKeys = []ebiten.Key{ebiten.KeyF1, ebiten.KeyF2, ebiten.KeyF3, ebiten.KeyF4, ebiten.KeyF5}
+
+// ChatLine is a message sent to the chat of the game.
+type ChatLine struct {
+	ID   int       `json:"id"`
+	At   time.Time `json:"at"`
+	From string    `json:"from"`
+	Text string    `json:"text"`
+}
+
+type apiSay struct {
+	From  string `json:"from"`
+	Text  string `json:"text"`
+	Emote string `json:"emote"` // instead of the text, see quickEmotes.
+}
+
+type apiChat struct {
+	Lines []ChatLine `json:"lines"`
+}
+
+// isEmote returns true if the text is of a quick emote.
+func isEmote(text string) bool {
+	for _, e := range quickEmotes {
+		if e.Text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// emoteText returns the text of the quick emote by its name.
+func emoteText(name string) (string, bool) {
+	for _, e := range quickEmotes {
+		if strings.EqualFold(e.Name, name) {
+			return e.Text, true
+		}
+	}
+	return "", false
+}
+
+// say adds the line to the chat of the game.
+func (sg *serverGame) say(from, text string) ChatLine {
+	sg.lastLine++
+	line := ChatLine{ID: sg.lastLine, At: time.Now(), From: from, Text: text}
+	sg.chat = append(sg.chat, line)
+	if len(sg.chat) > maxChatLines {
+		sg.chat = sg.chat[len(sg.chat)-maxChatLines:]
+	}
+	return line
+}
+
+// postChat sends the message to the chat of the game.
+// Both the player and the spectators may talk, so the sender names itself.
+func (s *Server) postChat(r *http.Request) (any, error) {
+	var req apiSay
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("bad message: %v", err)
+	}
+	req.From = strings.TrimSpace(req.From)
+	if req.From == "" || utf8.RuneCountInString(req.From) > maxChatName {
+		return nil, badRequest("the sender name must have 1 to %d letters", maxChatName)
+	}
+	text := strings.TrimSpace(req.Text)
+	if req.Emote != "" {
+		var ok bool
+		if text, ok = emoteText(req.Emote); !ok {
+			return nil, badRequest("unknown emote %q", req.Emote)
+		}
+	}
+	if text == "" || utf8.RuneCountInString(text) > maxChatText {
+		return nil, badRequest("the message must have 1 to %d letters", maxChatText)
+	}
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	return sg.say(req.From, text), nil
+}
+
+// getChat returns the chat lines after the id given by "since",
+// except those of the senders listed in "mute".
+func (s *Server) getChat(r *http.Request) (any, error) {
+	since := 0
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = strconv.Atoi(v); err != nil {
+			return nil, badRequest("bad since %q", v)
+		}
+	}
+	muted := make(map[string]bool)
+	for _, name := range strings.Split(r.URL.Query().Get("mute"), ",") {
+		muted[strings.TrimSpace(name)] = true
+	}
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	chat := apiChat{Lines: []ChatLine{}}
+	for _, line := range sg.chat {
+		if line.ID > since && !muted[line.From] {
+			chat.Lines = append(chat.Lines, line)
+		}
+	}
+	return chat, nil
+}
+
+// Chat is the chat panel of the window: the history, the input line and the muted senders.
+type Chat struct {
+	Name   string // of the sender.
+	Lines  []ChatLine
+	Muted  map[string]bool
+	Input  []rune
+	Typing bool
+
+	post   func(req apiSay) error // sends the message to the others.
+	lines  chan []ChatLine
+	errors chan error
+}
+
+// ChatLink is the connection to the peer carrying the chat, like the bot protocol,
+// so that the player talks to whoever plays the peer.
+type ChatLink interface {
+	// Say sends the message of the player to the peer.
+	Say(text string) error
+	// Heard returns the messages of the peer. It is closed when the peer is gone.
+	Heard() <-chan string
+}
+
+func newChat(name string) *Chat {
+	return &Chat{
+		Name:   name,
+		Muted:  make(map[string]bool),
+		lines:  make(chan []ChatLine),
+		errors: make(chan error, 1),
+	}
+}
+
+// NewChat returns the chat of the game served by the HTTP API, for the spectators.
+func NewChat(gameURL, name string) *Chat {
+	url := strings.TrimRight(gameURL, "/") + "/chat"
+	c := newChat(name)
+	c.post = func(req apiSay) error {
+		return apiCall("POST", url, req, nil)
+	}
+	go c.poll(url)
+	return c
+}
+
+// NewPeerChat returns the chat with the peer over the link, for the player.
+// The peer does not echo the messages, so the own ones are added as sent.
+func NewPeerChat(link ChatLink, name, peer string) *Chat {
+	c := newChat(name)
+	c.post = func(req apiSay) error {
+		if req.Emote != "" {
+			req.Text, _ = emoteText(req.Emote)
+		}
+		if err := link.Say(req.Text); err != nil {
+			return err
+		}
+		c.lines <- []ChatLine{{At: time.Now(), From: req.From, Text: req.Text}}
+		return nil
+	}
+	go func() {
+		for text := range link.Heard() {
+			c.lines <- []ChatLine{{At: time.Now(), From: peer, Text: text}}
+		}
+	}()
+	return c
+}
+
+// poll fetches the new chat lines every second.
+func (c *Chat) poll(url string) {
+	since := 0
+	for ; ; time.Sleep(time.Second) {
+		var chat apiChat
+		if err := apiCall("GET", fmt.Sprintf("%s?since=%d", url, since), nil, &chat); err != nil {
+			c.errors <- err
+			continue
+		}
+		if len(chat.Lines) > 0 {
+			since = chat.Lines[len(chat.Lines)-1].ID
+			c.lines <- chat.Lines
+		}
+	}
+}
+
+// send posts the message without blocking the window.
+func (c *Chat) send(req apiSay) {
+	req.From = c.Name
+	go func() {
+		if err := c.post(req); err != nil {
+			c.errors <- err
+		}
+	}()
+}
+
+// apiCall calls the API with the request body encoded as JSON, and decodes the result into res.
+func apiCall(method, url string, req, res any) error {
+	var body io.Reader
+	if req != nil {
+		b, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	hr, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(hr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct{ Error string }
+		if json.Unmarshal(b, &e) != nil || e.Error == "" {
+			e.Error = resp.Status
+		}
+		return fmt.Errorf("%s", e.Error)
+	}
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(b, res)
+}
+
+// update handles the keys of the chat, and returns the status to show, if any.
+// The chat control starts typing, Enter sends the line, Escape cancels it, F1-F5 send the emotes.
+// The lines "/mute name" and "/unmute name" are not sent but mute the sender.
+func (c *Chat) update(g *Game) string {
+	var status string
+	select {
+	case lines := <-c.lines:
+		c.Lines = append(c.Lines, lines...)
+	case err := <-c.errors:
+		status = g.tr("Chat: %v", err)
+	default:
+	}
+	for i, k := range emoteKeys {
+		if inpututil.IsKeyJustPressed(k) {
+			c.send(apiSay{Emote: quickEmotes[i].Name})
+		}
+	}
+	if !c.Typing {
+		g.keys = inpututil.AppendJustPressedKeys(g.keys[:0])
+		for _, k := range g.keys {
+			c.Typing = c.Typing || g.Keymap.control(k) == ControlChat
+		}
+		return status
+	}
+	c.Input = ebiten.AppendInputChars(c.Input)
+	if len(c.Input) > maxChatText {
+		c.Input = c.Input[:maxChatText]
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(c.Input) > 0:
+		c.Input = c.Input[:len(c.Input)-1]
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		c.Input, c.Typing = nil, false
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		status = c.command(g, strings.TrimSpace(string(c.Input)))
+		c.Input, c.Typing = nil, false
+	}
+	return status
+}
+
+// command sends the line typed, or mutes the sender.
+func (c *Chat) command(g *Game, line string) string {
+	if name, ok := strings.CutPrefix(line, "/mute "); ok {
+		c.Muted[strings.TrimSpace(name)] = true
+		return g.tr("Muted %s", strings.TrimSpace(name))
+	}
+	if name, ok := strings.CutPrefix(line, "/unmute "); ok {
+		delete(c.Muted, strings.TrimSpace(name))
+		return g.tr("Unmuted %s", strings.TrimSpace(name))
+	}
+	if line != "" {
+		c.send(apiSay{Text: line})
+	}
+	return ""
+}
+
+// draw draws the last lines not muted and the input line below the boards.
+// The lines too long for the panel are cut.
+func (c *Chat) draw(g *Game, screen *ebiten.Image, left, top int) {
+	var shown []string
+	for _, line := range c.Lines {
+		if c.Muted[line.From] {
+			continue
+		}
+		text := line.Text
+		if isEmote(text) {
+			text = g.tr(text)
+		}
+		shown = append(shown, fmt.Sprintf("%s: %s", line.From, text))
+	}
+	shown = shown[max(0, len(shown)-chatRows):]
+	prompt := g.tr("%s to chat, F1-F5 for emotes", g.Keymap.keyNames(ControlChat))
+	if c.Typing {
+		prompt = "> " + string(c.Input) + "_"
+	}
+	face := g.Theme.face(cellSize * 0.5)
+	w, _ := g.Grid.pixelSize(g.Rules.Weapons)
+	for i, s := range append(shown, prompt) {
+		s = fitText(s, face, float64(w-cellSize/2))
+		g.hudText(screen, s, face, float32(left)+cellBorder+cellSize*0.2, float32(top+i*chatLineSize))
+	}
+}
+
+// chatHeight is the height of the chat panel.
+func chatHeight() int {
+	return (chatRows+1)*chatLineSize + cellBorder
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeLink is the peer answering every message at once.
+type fakeLink struct {
+	said  []string
+	heard chan string
+}
+
+func (l *fakeLink) Say(text string) error {
+	l.said = append(l.said, text)
+	l.heard <- "re: " + text
+	return nil
+}
+
+func (l *fakeLink) Heard() <-chan string {
+	return l.heard
+}
+
+func TestPeerChat(t *testing.T) {
+	link := &fakeLink{heard: make(chan string, 1)}
+	c := NewPeerChat(link, "alice", "bot")
+	var got []ChatLine
+	for _, req := range []apiSay{{Text: "hi"}, {Emote: "gg"}} {
+		c.send(req)
+		for range 2 {
+			select {
+			case lines := <-c.lines:
+				got = append(got, lines...)
+			case <-time.After(time.Second):
+				t.Fatalf("no lines after %+v", req)
+			}
+		}
+	}
+	want := []ChatLine{{From: "alice", Text: "hi"}, {From: "bot", Text: "re: hi"}, {From: "alice", Text: "Good game!"}, {From: "bot", Text: "re: Good game!"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i, line := range got {
+		// The own line and the reply race, only the own one goes first.
+		if i%2 == 0 && (line.From != want[i].From || line.Text != want[i].Text) {
+			t.Errorf("line %d is %s: %s, want %s: %s", i, line.From, line.Text, want[i].From, want[i].Text)
+		}
+	}
+}
+
+func TestServerChat(t *testing.T) {
+	h := (&Server{}).Handler()
+	id := newGame(t, h, `{}`)
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"from": "alice", "text": "hi"}`, http.StatusOK},
+		{`{"from": "bob", "emote": "nice"}`, http.StatusOK},
+		{`{"from": "bob", "emote": "boo"}`, http.StatusBadRequest},
+		{`{"from": "", "text": "hi"}`, http.StatusBadRequest},
+		{`{"from": "alice", "text": "  "}`, http.StatusBadRequest},
+	} {
+		if code := call(t, h, "POST", "/games/"+id+"/chat", tc.body, nil); code != tc.code {
+			t.Errorf("POST chat %s: status %d, want %d", tc.body, code, tc.code)
+		}
+	}
+	var chat apiChat
+	call(t, h, "GET", "/games/"+id+"/chat?since=0&mute=alice", "", &chat)
+	if len(chat.Lines) != 1 || chat.Lines[0].Text != "Nice shot!" {
+		t.Errorf("got %+v, want the emote of bob", chat.Lines)
+	}
+	call(t, h, "GET", "/games/"+id+"/chat?since=1", "", &chat)
+	if len(chat.Lines) != 1 || chat.Lines[0].From != "bob" {
+		t.Errorf("got %+v after the first line, want the line of bob", chat.Lines)
+	}
+}
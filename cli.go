@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var cellGlyphs = map[Cell]byte{
+	CellEmpty: '.',
+	CellMiss:  'o',
+	CellMist:  '~',
+	CellShip:  '#',
+	CellFire:  'X',
+	CellSunk:  '*',
+	CellOily:  '.',
+	CellMine:  '+',
+	CellBlast: '!',
+	CellRock:  '^',
+}
+
+const cliHelp = `Commands:
+  C5            fire at the cell C5
+  radar C5      fire the weapon at the cell (with -weapons: radar, torpedo, cluster)
+  move C5 up    move the ship at C5 by one cell (with -moving: up, down, left, right)
+  board         print both boards
+  help          print this help
+  quit          stop the game
+Legend: . sea, ~ mist, # ship, o miss, X hit, * sunk, + mine, ! exploded mine, ^ island`
+
+var cliDirections = map[string]XY{
+	"up":    {0, -1},
+	"down":  {0, 1},
+	"left":  {-1, 0},
+	"right": {1, 0},
+}
+
+// runCLI plays the game with commands read line by line from in.
+// Unlike the other front-ends, the peer replies at once.
+func runCLI(g *Game, in io.Reader, out io.Writer) error {
+	defer g.finish()
+	if err := g.init(); err != nil {
+		return err
+	}
+	prompt := ""
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		prompt = "> "
+	}
+	fmt.Fprintln(out, g.Message)
+	fmt.Fprintln(out, `Type "help" for the list of commands.`)
+	scanner := bufio.NewScanner(in)
+	for fmt.Fprint(out, prompt); scanner.Scan(); fmt.Fprint(out, prompt) {
+		if err := g.cliCommand(scanner.Text(), out); err != nil {
+			g.Error = err
+		}
+		if g.Error == nil && g.WhoseTurn == SidePeer {
+			g.cliPeer(out)
+		}
+		if g.Error != nil {
+			fmt.Fprintln(out, g.Error)
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// cliCommand executes one command of the player.
+func (g *Game) cliCommand(line string, out io.Writer) error {
+	args := strings.Fields(strings.ToLower(line))
+	if len(args) == 0 {
+		return nil
+	}
+	switch args[0] {
+	case "help":
+		fmt.Fprintln(out, cliHelp)
+		return nil
+	case "board":
+		g.printBoards(out)
+		return nil
+	case "quit":
+		return fmt.Errorf("Stopped by player")
+	case "move":
+		if !g.Rules.Moving {
+			fmt.Fprintln(out, "Moving ships is not allowed, see -moving")
+			return nil
+		}
+		if len(args) != 3 {
+			fmt.Fprintln(out, "Usage: move C5 up")
+			return nil
+		}
+		xy, err := parseXY(args[1])
+		d, ok := cliDirections[args[2]]
+		if err != nil || !ok {
+			fmt.Fprintln(out, "Usage: move C5 up")
+			return nil
+		}
+		g.selectShip(xy)
+		if g.MovingShip == nil {
+			fmt.Fprintln(out, g.Message)
+			return nil
+		}
+		err = g.selfMoveShip(d)
+		fmt.Fprintln(out, g.Message)
+		return err
+	}
+	weapon := WeaponShot
+	if len(args) == 2 && g.Rules.Weapons {
+		w, ok := parseWeapon(args[0])
+		if !ok {
+			fmt.Fprintf(out, "Unknown weapon %q\n", args[0])
+			return nil
+		}
+		weapon = w
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(out, `Unknown command, type "help"`)
+		return nil
+	}
+	xy, err := parseXY(args[0])
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return nil
+	}
+	if !g.Arsenals[SideSelf].ready(weapon) {
+		fmt.Fprintln(out, g.Arsenals[SideSelf].status(g.Lang, weapon))
+		return nil
+	}
+	g.Arsenals[SideSelf].Selected = weapon
+	g.CursorSelf = xy
+	n, msg := len(g.History), g.Message
+	err = g.selfShoot()
+	g.printTurns("You", n, msg, out)
+	return err
+}
+
+// cliPeer plays the peer turn without waiting for the cursor to move.
+func (g *Game) cliPeer(out io.Writer) {
+	for g.WhoseTurn == SidePeer && g.Error == nil {
+		n, msg := len(g.History), g.Message
+		g.peerStep()
+		g.printTurns("Peer", n, msg, out)
+	}
+}
+
+// peerStep plays the next action of the peer at once.
+func (g *Game) peerStep() {
+	g.Tick += peerTicksPerAct
+	var err error
+	if g.Thinking != nil {
+		// Nobody watches the peer think, so wait for it.
+		r := <-g.Thinking
+		g.Thinking = nil
+		err = g.peerChosen(r.Action, r.Err)
+	} else {
+		g.CursorPeer = g.PeerToHit
+		err = g.updatePeer()
+	}
+	if err != nil {
+		g.Error = err
+	}
+}
+
+// printTurns prints the turns done since the history had n turns, like "Peer: shot C5: C5 hit",
+// and the message if it has changed from msg.
+func (g *Game) printTurns(who string, n int, msg string, out io.Writer) {
+	for _, t := range g.History[n:] {
+		switch {
+		case t.Action.Move:
+			// Nobody knows which ship of the peer has moved.
+			fmt.Fprintf(out, "%s: %s\n", who, Action{Move: true})
+		case len(t.Results) > 0:
+			fmt.Fprintf(out, "%s: %s: %s\n", who, t.Action, strings.Join(t.Results, ", "))
+		case t.Action.Weapon == WeaponShot && g.Boards[t.Side.opponent()].Cells[t.Action.Target.Y][t.Action.Target.X] == CellRock:
+			fmt.Fprintf(out, "%s: %s: %s island\n", who, t.Action, t.Action.Target)
+		case t.Action.Weapon == WeaponShot:
+			fmt.Fprintf(out, "%s: %s: %s already shot\n", who, t.Action, t.Action.Target)
+		default:
+			fmt.Fprintf(out, "%s: %s: no effect\n", who, t.Action)
+		}
+	}
+	if g.Message != msg {
+		fmt.Fprintln(out, g.Message)
+	}
+}
+
+// printBoards prints both boards as the player sees them.
+func (g *Game) printBoards(out io.Writer) {
+	printViews(out, g.views())
+}
+
+// printViews prints the views side by side.
+func printViews(out io.Writer, views [2]*View) {
+	for y := 0; y <= Ncells; y++ {
+		var sb strings.Builder
+		for _, v := range views {
+			if y == Ncells {
+				sb.WriteString("  ")
+				for x := 0; x < Ncells; x++ {
+					fmt.Fprintf(&sb, "%c ", 'A'+x)
+				}
+			} else {
+				fmt.Fprintf(&sb, "%c ", '1'+y)
+				for x := 0; x < Ncells; x++ {
+					fmt.Fprintf(&sb, "%c ", cellGlyphs[v.Cells[y][x]])
+				}
+			}
+			sb.WriteString("   ")
+		}
+		fmt.Fprintln(out, strings.TrimRight(sb.String(), " "))
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseXY(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want XY
+		ok   bool
+	}{
+		{"A1", XY{0, 0}, true},
+		{"C5", XY{2, 4}, true},
+		{"c5", XY{2, 4}, true},
+		{" h8\t", XY{7, 7}, true},
+		{"I1", XY{}, false},
+		{"A9", XY{}, false},
+		{"A0", XY{}, false},
+		{"@1", XY{}, false},
+		{"5C", XY{}, false},
+		{"C 5", XY{}, false},
+		{"C55", XY{}, false},
+		{"", XY{}, false},
+		{"В5", XY{}, false}, // Cyrillic.
+	} {
+		got, err := parseXY(tc.in)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("parseXY(%q) = %v, %v, want %v, ok %v", tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCLICommand(t *testing.T) {
+	g := testGame(t, Rules{}, viewBoard, viewBoard)
+	g.Peer = NewAIStrategy(g.Rules)
+	for _, tc := range []struct {
+		line string
+		want string
+	}{
+		{"", ""},
+		{"move C5 up", "Moving ships is not allowed, see -moving\n"},
+		{"radar C5", "Unknown command, type \"help\"\n"},
+		{"Z9", "bad cell \"Z9\", want A-H and 1-8\n"},
+		{"E6", "You: shot E6: E6 hit\n"},
+		{"E6", "You: shot E6: E6 already shot\n"},
+		{"D3", "You: shot D3: D3 island\n"},
+		{"e8", "You: shot E8: E8 miss\n"},
+	} {
+		var out strings.Builder
+		if err := g.cliCommand(tc.line, &out); err != nil {
+			t.Fatalf("%q: %v", tc.line, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.line, out.String(), tc.want)
+		}
+	}
+	if err := g.cliCommand("quit", &strings.Builder{}); err == nil {
+		t.Error("quit has not stopped the game")
+	}
+}
+
+// movingStrategy moves the ship at the cell to the right on every turn.
+type movingStrategy struct {
+	at XY
+}
+
+func (s *movingStrategy) Place(yard Shipyard) error {
+	return nil
+}
+
+func (s *movingStrategy) Act(own, v *View, a *Arsenal, canMove func(at, dir XY) bool) (Action, error) {
+	act := Action{Move: true, Target: s.at, Dir: XY{1, 0}}
+	s.at.X++
+	return act, nil
+}
+
+func (s *movingStrategy) Observe(own bool, act Action, mine, theirs *View) {}
+
+func (s *movingStrategy) End(outcome string) error {
+	return nil
+}
+
+func TestCLIPeerMove(t *testing.T) {
+	g := testGame(t, Rules{Moving: true}, viewBoard, viewBoard)
+	g.Peer = &movingStrategy{at: XY{4, 5}}
+	if err := g.peerToHit(); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	g.cliPeer(&out)
+	if want := "Peer: move\nThe peer has moved a ship\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if g.WhoseTurn != SideSelf || g.Boards[SidePeer].shipAt(XY{5, 5}) == nil {
+		t.Errorf("the ship of the peer has not moved")
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Control is what the player does by a key, a gamepad button or in the terminal.
+type Control int
+
+const (
+	ControlNone Control = iota
+	ControlUp
+	ControlDown
+	ControlLeft
+	ControlRight
+	ControlFire
+	ControlTarget // typing the cell like C5.
+	ControlCancel
+	ControlUndo // the last choice of the turn.
+	ControlMoving
+	ControlWeapon // the first weapon, the others follow.
+	ControlHint   = ControlWeapon + Control(numWeapons)
+	ControlCoords = ControlHint + 1
+	ControlOrder  = ControlHint + 2 // numbering all the shots.
+	ControlChat   = ControlHint + 3 // typing to the chat, handled by the chat.
+	ControlMenu   = ControlHint + 4
+	ControlQuit   = ControlHint + 5
+	numControls   = ControlHint + 6
+)
+
+const (
+	repeatDelay = gameTPS / 3  // ticks before the held direction repeats.
+	repeatEvery = gameTPS / 10 // ticks between the repeats.
+)
+
+var controlNames = map[Control]string{
+	ControlUp:     "up",
+	ControlDown:   "down",
+	ControlLeft:   "left",
+	ControlRight:  "right",
+	ControlFire:   "fire",
+	ControlTarget: "target",
+	ControlCancel: "cancel",
+	ControlUndo:   "undo",
+	ControlMoving: "moving",
+	ControlHint:   "hint",
+	ControlCoords: "coords",
+	ControlOrder:  "order",
+	ControlChat:   "chat",
+	ControlMenu:   "menu",
+	ControlQuit:   "quit",
+}
+
+func init() {
+	for w := Weapon(0); w < numWeapons; w++ {
+		controlNames[ControlWeapon+Control(w)] = fmt.Sprintf("weapon-%d", w+1)
+	}
+}
+
+func (c Control) String() string {
+	return controlNames[c]
+}
+
+func (c Control) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Control) UnmarshalText(text []byte) error {
+	for cc, name := range controlNames {
+		if name == string(text) {
+			*c = cc
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown control %q", text)
+}
+
+// repeats returns whether the direction control held for the ticks is repeated now.
+func repeats(ticks int) bool {
+	return ticks == 1 || (ticks > repeatDelay && (ticks-repeatDelay)%repeatEvery == 0)
+}
+
+// Keymap binds the keys to every control.
+type Keymap map[Control][]ebiten.Key
+
+// commonKeys are bound in every preset.
+var commonKeys = Keymap{
+	ControlFire:   {ebiten.KeySpace, ebiten.KeyEnter},
+	ControlTarget: {ebiten.KeyT},
+	ControlCancel: {ebiten.KeyEscape},
+	ControlUndo:   {ebiten.KeyU},
+	ControlMoving: {ebiten.KeyM},
+	ControlWeapon: {ebiten.KeyDigit1},
+	ControlCoords: {ebiten.KeyC},
+	ControlOrder:  {ebiten.KeyN},
+	ControlChat:   {ebiten.KeyBackquote},
+	ControlMenu:   {ebiten.KeyTab},
+	ControlQuit:   {ebiten.KeyQ},
+}
+
+// keyPresets are the bindings to start from.
+var keyPresets = map[string]Keymap{
+	"arrows": withCommonKeys(Keymap{
+		ControlUp:    {ebiten.KeyArrowUp},
+		ControlDown:  {ebiten.KeyArrowDown},
+		ControlLeft:  {ebiten.KeyArrowLeft},
+		ControlRight: {ebiten.KeyArrowRight},
+		ControlHint:  {ebiten.KeyH},
+	}),
+	"wasd": withCommonKeys(Keymap{
+		ControlUp:    {ebiten.KeyW, ebiten.KeyArrowUp},
+		ControlDown:  {ebiten.KeyS, ebiten.KeyArrowDown},
+		ControlLeft:  {ebiten.KeyA, ebiten.KeyArrowLeft},
+		ControlRight: {ebiten.KeyD, ebiten.KeyArrowRight},
+		ControlHint:  {ebiten.KeyH},
+	}),
+	"vim": withCommonKeys(Keymap{
+		ControlUp:    {ebiten.KeyK, ebiten.KeyArrowUp},
+		ControlDown:  {ebiten.KeyJ, ebiten.KeyArrowDown},
+		ControlLeft:  {ebiten.KeyH, ebiten.KeyArrowLeft},
+		ControlRight: {ebiten.KeyL, ebiten.KeyArrowRight},
+		ControlHint:  {ebiten.KeySlash},
+	}),
+}
+
+var presetNames = []string{"arrows", "wasd", "vim"}
+
+// withCommonKeys adds the common keys and the weapon digits to the preset.
+func withCommonKeys(km Keymap) Keymap {
+	for c, keys := range commonKeys {
+		km[c] = keys
+	}
+	for w := Weapon(1); w < numWeapons; w++ {
+		km[ControlWeapon+Control(w)] = []ebiten.Key{ebiten.KeyDigit1 + ebiten.Key(w)}
+	}
+	return km
+}
+
+// control returns the control bound to the key.
+func (km Keymap) control(k ebiten.Key) Control {
+	for c := ControlNone; c < numControls; c++ {
+		if slices.Contains(km[c], k) {
+			return c
+		}
+	}
+	return ControlNone
+}
+
+// keyNames returns the names of the keys bound to the control, like "Space/Enter".
+func (km Keymap) keyNames(c Control) string {
+	var s string
+	for i, k := range km[c] {
+		if i > 0 {
+			s += "/"
+		}
+		s += k.String()
+	}
+	return s
+}
+
+// handleKeys handles the keys just pressed, and repeats the held directions.
+func (g *Game) handleKeys() error {
+	if g.Menu != nil {
+		g.Menu.update(g)
+		return nil
+	}
+	if g.Target != nil {
+		return g.updateTarget()
+	}
+	g.keys = inpututil.AppendPressedKeys(g.keys[:0])
+	for _, k := range g.keys {
+		c := g.Keymap.control(k)
+		d := inpututil.KeyPressDuration(k)
+		if c == ControlNone || (d != 1 && (c > ControlRight || !repeats(d))) {
+			continue
+		}
+		if err := g.handleControl(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleControl handles the control of the player. It is shared by all front-ends.
+func (g *Game) handleControl(c Control) error {
+	if g.Menu != nil {
+		g.Menu.handle(g, c)
+		return nil
+	}
+	switch c {
+	case ControlQuit:
+		// Special handling even during peer turn.
+		return errors.New(g.tr("Stopped by player"))
+	case ControlCoords:
+		g.HideCoords = !g.HideCoords
+		return nil
+	case ControlOrder:
+		g.ShowOrder = !g.ShowOrder
+		return nil
+	case ControlMenu:
+		g.Menu = &Menu{}
+		return nil
+	}
+	if g.PlacingMines {
+		return g.handleMineControl(c)
+	}
+	if g.WhoseTurn == SidePeer {
+		return nil
+	}
+	if c == ControlMoving && g.Rules.Moving {
+		g.toggleMoving()
+		return nil
+	}
+	if c == ControlUndo {
+		g.undo()
+		return nil
+	}
+	if g.Moving {
+		return g.handleMoveControl(c)
+	}
+	if w := Weapon(c - ControlWeapon); g.Rules.Weapons && w >= 0 && w < numWeapons {
+		g.selectWeapon(w)
+	}
+	switch c {
+	case ControlUp:
+		g.CursorSelf.Y = (g.CursorSelf.Y - 1 + Ncells) % Ncells
+	case ControlDown:
+		g.CursorSelf.Y = (g.CursorSelf.Y + 1) % Ncells
+	case ControlLeft:
+		g.CursorSelf.X = (g.CursorSelf.X - 1 + Ncells) % Ncells
+	case ControlRight:
+		g.CursorSelf.X = (g.CursorSelf.X + 1) % Ncells
+	case ControlFire:
+		return g.selfShoot()
+	case ControlTarget:
+		g.Target = []rune{}
+		g.Message = g.tr("Type the cell, like %s", g.Lang.cell(XY{2, 4}))
+	case ControlCancel:
+		g.TapArmed = false
+	case ControlHint:
+		g.hint()
+	}
+	return nil
+}
+
+// hint moves the cursor to the cell the computer would shoot at.
+func (g *Game) hint() {
+	xy, err := huntLargestStrategy(g.Rand, g.Boards[SidePeer].view(SideSelf))
+	if err != nil {
+		g.Message = g.tr("No hint")
+		return
+	}
+	g.CursorSelf = xy
+	g.Message = g.tr("Hint: try %s", g.Lang.cell(xy))
+}
+
+// Choice is what the player has chosen in the turn before firing or moving the ship.
+type Choice struct {
+	Turn       int // the length of the history when chosen.
+	Undos      int // the length of the undo list when chosen.
+	CursorSelf XY
+	CursorOwn  XY
+	Weapon     Weapon
+	Moving     bool
+	MovingShip *Ship
+	TapArmed   bool
+}
+
+func (g *Game) choice() Choice {
+	return Choice{
+		Turn:       len(g.History),
+		Undos:      len(g.Undo),
+		CursorSelf: g.CursorSelf,
+		CursorOwn:  g.CursorOwn,
+		Weapon:     g.Arsenals[SideSelf].Selected,
+		Moving:     g.Moving,
+		MovingShip: g.MovingShip,
+		TapArmed:   g.TapArmed,
+	}
+}
+
+// remember keeps the choice made before the input for the undo. The steps of the cursor
+// are not kept, only its jumps, like to the hint. The choices are forgotten with the turn.
+func (g *Game) remember(before Choice) {
+	after := g.choice()
+	switch {
+	case after.Turn != before.Turn:
+		g.Undo = nil
+		return
+	case after.Undos != before.Undos || after == before:
+		// Undone, or nothing is chosen.
+		return
+	}
+	after.CursorSelf, after.CursorOwn = before.CursorSelf, before.CursorOwn
+	if after == before && nextTo(before.CursorSelf, g.CursorSelf) && nextTo(before.CursorOwn, g.CursorOwn) {
+		return
+	}
+	g.Undo = append(g.Undo, before)
+}
+
+// nextTo returns true if the cells are the same or the neighbours, wrapping around like the cursor.
+func nextTo(a, b XY) bool {
+	near := func(d int) bool {
+		d = (d + Ncells) % Ncells
+		return d == 0 || d == 1 || d == Ncells-1
+	}
+	return near(a.X-b.X) && near(a.Y-b.Y)
+}
+
+// undo takes back the last choice of the turn.
+func (g *Game) undo() {
+	n := len(g.Undo)
+	if n == 0 || g.Undo[n-1].Turn != len(g.History) {
+		g.Undo = nil
+		g.Message = g.tr("Nothing to undo")
+		return
+	}
+	c := g.Undo[n-1]
+	g.Undo = g.Undo[:n-1]
+	g.CursorSelf, g.CursorOwn = c.CursorSelf, c.CursorOwn
+	g.Arsenals[SideSelf].Selected = c.Weapon
+	g.Moving, g.MovingShip, g.TapArmed = c.Moving, c.MovingShip, c.TapArmed
+	g.Message = g.tr("Undone")
+}
+
+// updateTarget handles the cell typed after the target control.
+// The cursor jumps to the cell as soon as it is typed, Enter fires at it,
+// and Escape stops typing.
+func (g *Game) updateTarget() error {
+	g.keys = inpututil.AppendJustPressedKeys(g.keys[:0])
+	for _, k := range g.keys {
+		switch k {
+		case ebiten.KeyEscape:
+			g.Target = nil
+			return nil
+		case ebiten.KeyBackspace:
+			if len(g.Target) > 0 {
+				g.Target = g.Target[:len(g.Target)-1]
+			}
+		case ebiten.KeyEnter:
+			xy, err := g.Lang.parseCell(string(g.Target))
+			if err != nil {
+				g.Message = err.Error()
+				return nil
+			}
+			g.Target = nil
+			g.CursorSelf = xy
+			if g.WhoseTurn != SideSelf {
+				return nil
+			}
+			return g.selfShoot()
+		}
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if len(g.Target) < 2 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			g.Target = append(g.Target, unicode.ToUpper(r))
+		}
+	}
+	if len(g.Target) < 2 {
+		g.Message = g.tr("Type the cell, like %s", g.Lang.cell(XY{2, 4}))
+		return nil
+	}
+	xy, err := g.Lang.parseCell(string(g.Target))
+	if err != nil {
+		g.Message = err.Error()
+		return nil
+	}
+	g.CursorSelf = xy
+	g.Message = g.tr("Enter to fire at %s", g.Lang.cell(xy))
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+// press handles the control like the terminal does, remembering the choice for the undo.
+func press(t *testing.T, g *Game, c Control) {
+	t.Helper()
+	before := g.choice()
+	if err := g.handleControl(c); err != nil {
+		t.Fatalf("%s: %v", c, err)
+	}
+	g.remember(before)
+}
+
+func TestUndo(t *testing.T) {
+	g := NewGame(Rules{Weapons: true, Moving: true}, NewAIStrategy(Rules{}))
+	if err := g.init(); err != nil {
+		t.Fatal(err)
+	}
+	press(t, g, ControlRight)
+	press(t, g, ControlDown)
+	if len(g.Undo) != 0 {
+		t.Errorf("the steps of the cursor are remembered: %v", g.Undo)
+	}
+	start := g.CursorSelf
+	press(t, g, ControlWeapon+Control(WeaponRadar))
+	press(t, g, ControlMoving)
+	press(t, g, ControlUndo)
+	if g.Moving || g.Arsenals[SideSelf].Selected != WeaponRadar {
+		t.Errorf("after the first undo: moving %v, weapon %s, want the radar selected", g.Moving, g.Arsenals[SideSelf].Selected)
+	}
+	press(t, g, ControlUndo)
+	if g.Arsenals[SideSelf].Selected != WeaponShot || g.CursorSelf != start {
+		t.Errorf("after the second undo: weapon %s at %s, want the shot at %s", g.Arsenals[SideSelf].Selected, g.CursorSelf, start)
+	}
+	press(t, g, ControlUndo)
+	if g.Message != "Nothing to undo" {
+		t.Errorf("got message %q after undoing everything", g.Message)
+	}
+
+	press(t, g, ControlHint)
+	press(t, g, ControlFire)
+	if len(g.Undo) != 0 {
+		t.Errorf("the choices are remembered after the shot: %v", g.Undo)
+	}
+}
+
+func TestNextTo(t *testing.T) {
+	for _, tc := range []struct {
+		a, b XY
+		want bool
+	}{
+		{XY{3, 3}, XY{3, 3}, true},
+		{XY{3, 3}, XY{4, 2}, true},
+		{XY{0, 5}, XY{Ncells - 1, 5}, true}, // wrapped around.
+		{XY{3, 3}, XY{5, 3}, false},
+		{XY{0, 0}, XY{4, 4}, false},
+	} {
+		if got := nextTo(tc.a, tc.b); got != tc.want {
+			t.Errorf("nextTo(%s, %s) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
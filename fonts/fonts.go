@@ -8,4 +8,13 @@ import (
 var (
 	//go:embed PTS55F.ttf
 	PTSansRegular []byte
+
+	//go:embed PTS75F.ttf
+	PTSansBold []byte
+
+	//go:embed PTN57F.ttf
+	PTSansNarrow []byte
+
+	//go:embed PTC55F.ttf
+	PTSansCaption []byte
 )
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	stickThreshold = 0.5
+
+	gamepadHelp = "D-pad: cursor, A: fire, B: cancel, X: hint, Y: move ships, LB/RB: weapon, Start: menu"
+)
+
+// gamepadDirs are the directions of the D-pad and the left stick.
+var gamepadDirs = []struct {
+	Button  ebiten.StandardGamepadButton
+	Axis    ebiten.StandardGamepadAxis
+	Sign    float64
+	Control Control
+}{
+	{ebiten.StandardGamepadButtonLeftTop, ebiten.StandardGamepadAxisLeftStickVertical, -1, ControlUp},
+	{ebiten.StandardGamepadButtonLeftBottom, ebiten.StandardGamepadAxisLeftStickVertical, 1, ControlDown},
+	{ebiten.StandardGamepadButtonLeftLeft, ebiten.StandardGamepadAxisLeftStickHorizontal, -1, ControlLeft},
+	{ebiten.StandardGamepadButtonLeftRight, ebiten.StandardGamepadAxisLeftStickHorizontal, 1, ControlRight},
+}
+
+// gamepadControls are the buttons of the standard layout and their controls.
+var gamepadControls = map[ebiten.StandardGamepadButton]Control{
+	ebiten.StandardGamepadButtonRightBottom: ControlFire,   // A
+	ebiten.StandardGamepadButtonRightRight:  ControlCancel, // B
+	ebiten.StandardGamepadButtonRightLeft:   ControlHint,   // X
+	ebiten.StandardGamepadButtonRightTop:    ControlMoving, // Y
+	ebiten.StandardGamepadButtonCenterRight: ControlMenu,   // Start
+}
+
+// handleGamepads handles the gamepads with the standard layout.
+// The gamepads may be connected and disconnected at any time.
+func (g *Game) handleGamepads() error {
+	for _, id := range g.gamepads {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			g.Message = g.tr("Gamepad disconnected")
+		}
+	}
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		g.Message = g.tr("Gamepad %s connected, press Back for help", ebiten.GamepadName(id))
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			g.Message = g.tr("Gamepad %s is not supported", ebiten.GamepadName(id))
+		}
+	}
+	g.gamepads = ebiten.AppendGamepadIDs(g.gamepads[:0])
+	var held [4]bool
+	for _, id := range g.gamepads {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for i, d := range gamepadDirs {
+			held[i] = held[i] || ebiten.IsStandardGamepadButtonPressed(id, d.Button) ||
+				ebiten.StandardGamepadAxisValue(id, d.Axis)*d.Sign > stickThreshold
+		}
+		for b, c := range gamepadControls {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, b) {
+				if err := g.handleControl(c); err != nil {
+					return err
+				}
+			}
+		}
+		switch {
+		case inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterLeft):
+			g.Message = g.tr(gamepadHelp)
+		case inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonFrontTopLeft):
+			g.cycleWeapon(-1)
+		case inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonFrontTopRight):
+			g.cycleWeapon(1)
+		}
+	}
+	// The held direction moves the cursor once, then repeats after the delay.
+	for i, d := range gamepadDirs {
+		if !held[i] {
+			g.gamepadHeld[i] = 0
+			continue
+		}
+		g.gamepadHeld[i]++
+		if repeats(g.gamepadHeld[i]) {
+			if err := g.handleControl(d.Control); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cycleWeapon selects the next ready weapon of the player in the direction.
+func (g *Game) cycleWeapon(step int) {
+	if !g.Rules.Weapons || g.WhoseTurn != SideSelf {
+		return
+	}
+	a := g.Arsenals[SideSelf]
+	w := a.Selected
+	for range numWeapons {
+		w = (w + Weapon(step) + numWeapons) % numWeapons
+		if a.ready(w) {
+			g.selectWeapon(w)
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Turn is one action done in the game.
+type Turn struct {
+	Side    Side
+	Action  Action
+	Results []string // what has changed on the board, like "C5 hit", or on own board by the mine, like "blast B2 hit".
+	Cells   []XY     // the cells changed by the shot.
+}
+
+// record adds the action of the side to the history.
+func (g *Game) record(side Side, act Action, results []string, cells []XY) {
+	g.History = append(g.History, Turn{Side: side, Action: act, Results: results, Cells: cells})
+}
+
+// shotStats returns how many shots the side has fired, and how many of them have hit a ship.
+// The radar scans are not shots.
+func (g *Game) shotStats(side Side) (shots, hits int) {
+	for _, t := range g.History {
+		if t.Side != side || t.Action.Move || t.Action.Weapon == WeaponRadar {
+			continue
+		}
+		shots++
+		for _, r := range t.Results {
+			// The damage of own ship by the mine is not a hit.
+			if !strings.HasPrefix(r, "blast ") && (strings.HasSuffix(r, " hit") || strings.HasSuffix(r, " sunk")) {
+				hits++
+				break
+			}
+		}
+	}
+	return shots, hits
+}
+
+// viewChanges describes what has changed on the board, and returns the cells changed.
+func viewChanges(before, after *View) ([]string, []XY) {
+	var changes []string
+	var cells []XY
+	if len(after.Scans) > len(before.Scans) {
+		sc := after.Scans[len(after.Scans)-1]
+		found := "clear"
+		if sc.Found {
+			found = "contact"
+		}
+		changes = append(changes, fmt.Sprintf("%s %s", sc.Center, found))
+	}
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			c := after.Cells[y][x]
+			if name, ok := botCells[c]; ok && c != before.Cells[y][x] {
+				changes = append(changes, fmt.Sprintf("%s %s", XY{x, y}, name))
+				cells = append(cells, XY{x, y})
+			}
+		}
+	}
+	return changes, cells
+}
+
+// shotTurns returns the number of the turn, counted from 1, which has changed
+// every cell of the board of the side, or 0 for the cells not shot at.
+func (g *Game) shotTurns(side Side) *[Ncells][Ncells]int {
+	var turns [Ncells][Ncells]int
+	for i, t := range g.History {
+		if t.Side == side {
+			continue
+		}
+		for _, xy := range t.Cells {
+			if turns[xy.Y][xy.X] == 0 {
+				turns[xy.Y][xy.X] = i + 1
+			}
+		}
+	}
+	return &turns
+}
+
+// recentShots returns the numbers of the last n turns which have changed the board of the side,
+// the last one first.
+func (g *Game) recentShots(side Side, n int) []int {
+	var recent []int
+	for i := len(g.History) - 1; i >= 0 && len(recent) < n; i-- {
+		if t := g.History[i]; t.Side != side && len(t.Cells) > 0 {
+			recent = append(recent, i+1)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	hudLines    = 8 // of the status panel under the boards.
+	hudLineSize = cellSize * 3 / 5
+	iconCell    = 8 // the size of a cell of the ship icons.
+)
+
+// hudHeight is the height of the status panel.
+func hudHeight() int {
+	return hudLines*hudLineSize + cellBorder
+}
+
+// turnText tells whose turn it is.
+func (g *Game) turnText() string {
+	switch {
+	case g.Error != nil:
+		return g.tr("Game over")
+	case g.PlacingMines:
+		return g.tr("Hiding the mines")
+	case g.WhoseTurn == SideSelf:
+		return g.tr("Your turn")
+	}
+	return g.tr("The peer's turn")
+}
+
+// statsText tells how the side shoots, like "You: 12 shots, 5 hits, 42%".
+func (g *Game) statsText(side Side) string {
+	shots, hits := g.shotStats(side)
+	accuracy := 0
+	if shots > 0 {
+		accuracy = 100 * hits / shots
+	}
+	who := g.tr("You")
+	if side == SidePeer {
+		who = g.tr("Peer")
+	}
+	return g.tr("%s: %s, %s, %d%%", who,
+		g.trn(shots, "%d shot", "%d shots", shots), g.trn(hits, "%d hit", "%d hits", hits), accuracy)
+}
+
+// drawHUD draws the status panel below the boards: whose turn it is, the fleets
+// with the shots fired at them, and the last shots.
+// Under the side by side boards every board has its own column,
+// and the stacked boards have their rows one after another.
+func (g *Game) drawHUD(screen *ebiten.Image, left, top int) {
+	x0 := float32(left) + cellBorder + cellSize*0.2
+	line := func(i int) float32 {
+		return float32(top + i*hudLineSize)
+	}
+	face := g.Theme.face(cellSize * 0.5)
+	width := float64(cellPos(Ncells) - cellSize/2)
+	g.hudText(screen, g.turnText(), face, x0, line(0))
+	row := 1
+	for side := SideSelf; side <= SidePeer; side++ {
+		x := x0
+		if !g.Grid.Portrait {
+			px, _ := g.Grid.origin(0, 0, side)
+			x = float32(px) + cellSize*0.2
+		} else if side == SidePeer {
+			row += 2
+		}
+		b := g.Boards[side]
+		g.hudText(screen, fitText(g.statsText(side.opponent()), face, width), face, x, line(row))
+		g.drawFleet(screen, x, line(row+1)+(hudLineSize-iconCell)/2, b)
+	}
+	row += 2
+	events := g.Events[max(0, len(g.Events)-(hudLines-row)):]
+	w, _ := g.Grid.pixelSize(g.Rules.Weapons)
+	for i, s := range events {
+		g.hudText(screen, fitText(s, face, float64(w-cellSize/2)), face, x0, line(row+i))
+	}
+}
+
+// hudText draws the line of the status panel at the position of its top left corner.
+func (g *Game) hudText(screen *ebiten.Image, s string, face text.Face, x, y float32) {
+	opts := &text.DrawOptions{}
+	opts.GeoM.Translate(float64(x), float64(y))
+	opts.ColorScale.ScaleWithColor(color.RGBA(g.Theme.Text))
+	text.Draw(screen, s, face, opts)
+}
+
+// fitText cuts the end of the text off, so that it is no wider than the width.
+func fitText(s string, face text.Face, width float64) string {
+	if w, _ := text.Measure(s, face, 0); w <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 {
+		r = r[:len(r)-1]
+		cut := strings.TrimSpace(string(r)) + "…"
+		if w, _ := text.Measure(cut, face, 0); w <= width {
+			return cut
+		}
+	}
+	return ""
+}
+
+// drawFleet draws the ships of the board as the small icons, the largest first.
+// The sunk ships are crossed out.
+func (g *Game) drawFleet(screen *ebiten.Image, x, y float32, b *Board) {
+	for size := maxShipSize; size > 0; size-- {
+		for i := 0; i < fleetSize(size); i++ {
+			sunk := i >= b.Ships[size-1]
+			cell := CellShip
+			if sunk {
+				cell = CellSunk
+			}
+			w := float32(size * iconCell)
+			for k := 0; k < size; k++ {
+				vector.DrawFilledRect(screen, x+float32(k*iconCell), y, iconCell-1, iconCell-1,
+					color.RGBA(g.Theme.Cells[cell].Fill), false)
+			}
+			vector.StrokeRect(screen, x, y, w-1, iconCell-1, 1, color.RGBA(g.Theme.Hull), false)
+			if sunk {
+				vector.StrokeLine(screen, x-1, y+iconCell, x+w, y-1, 2, color.RGBA(g.Theme.Text), true)
+			}
+			x += w + iconCell
+		}
+	}
+}
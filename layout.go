@@ -0,0 +1,103 @@
+package main
+
+// Grid places the boards on the screen, measured in cells.
+// The boards are side by side in the landscape window and stacked in the portrait one.
+// The row 0 is for the message, then go the rows of the board, its letters,
+// and the weapon bar under the peer board.
+type Grid struct {
+	Portrait  bool
+	Left, Top int // the screen position of the grid, centered in the window.
+}
+
+// gridXY returns the column and the row of the cell (X,Y) of the board in the grid.
+func (gr Grid) gridXY(x, y int, side Side) (int, int) {
+	if gr.Portrait {
+		return x, int(side)*(Ncells+1) + y + 1
+	}
+	return int(side)*(Ncells+1) + x, y + 1
+}
+
+// size returns the number of the columns and the rows in the grid.
+func (gr Grid) size(weapons bool) (int, int) {
+	cols, rows := gr.gridXY(Ncells, Ncells, SidePeer)
+	if weapons {
+		rows++
+	}
+	if gr.Portrait {
+		// The numbers column is right of the boards.
+		cols++
+	}
+	return cols, rows + 1
+}
+
+// pixelSize returns the size of the grid on the screen.
+func (gr Grid) pixelSize(weapons bool) (int, int) {
+	cols, rows := gr.size(weapons)
+	return cellPos(cols), cellPos(rows)
+}
+
+// origin returns the screen position of the top left corner of the cell (X,Y) on the board.
+func (gr Grid) origin(x, y int, side Side) (int, int) {
+	col, row := gr.gridXY(x, y, side)
+	return gr.Left + cellPos(col), gr.Top + cellPos(row)
+}
+
+// cellOrigin is the origin for drawing.
+func (gr Grid) cellOrigin(x, y int, side Side) (float32, float32) {
+	px, py := gr.origin(x, y, side)
+	return float32(px), float32(py)
+}
+
+// cellAt returns the cell of the board at the screen position.
+// The cell may be outside of the board, like the letters or the weapon bar.
+func (gr Grid) cellAt(px, py int, side Side) XY {
+	x0, y0 := gr.origin(0, 0, side)
+	return XY{floorDiv(px-x0, cellSize+cellBorder), floorDiv(py-y0, cellSize+cellBorder)}
+}
+
+// inBoard returns whether the screen position is on the board.
+func (gr Grid) inBoard(px, py int, side Side) bool {
+	xy := gr.cellAt(px, py, side)
+	return xy.X >= 0 && xy.X < Ncells && xy.Y >= 0 && xy.Y < Ncells
+}
+
+// pos2Cell returns the cell of the board nearest to the screen position.
+func (gr Grid) pos2Cell(px, py int, side Side) XY {
+	xy := gr.cellAt(px, py, side)
+	return XY{min(max(xy.X, 0), Ncells-1), min(max(xy.Y, 0), Ncells-1)}
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// fit chooses the grid for the window with the extra height under it,
+// and returns the screen size scaled to fill the whole window and zoomed.
+func (g *Game) fit(oW, oH, extra int) (int, int) {
+	best, bestScale := Grid{}, 0.0
+	for _, gr := range []Grid{{Portrait: false}, {Portrait: true}} {
+		w, h := gr.pixelSize(g.Rules.Weapons)
+		scale := min(float64(oW)/float64(w), float64(oH)/float64(h+extra))
+		if scale > bestScale {
+			best, bestScale = gr, scale
+		}
+	}
+	w, h := best.pixelSize(g.Rules.Weapons)
+	h += extra
+	if bestScale <= 0 {
+		g.Grid = best
+		return w, h
+	}
+	// The screen has the aspect of the window, so that it is not letterboxed.
+	scale := bestScale * g.zoom()
+	sw, sh := int(float64(oW)/scale), int(float64(oH)/scale)
+	// The zoomed boards are dragged no further than their edges.
+	g.Pan[0] = min(max(g.Pan[0], -float64(max(w-sw, 0))/2), float64(max(w-sw, 0))/2)
+	g.Pan[1] = min(max(g.Pan[1], -float64(max(h-sh, 0))/2), float64(max(h-sh, 0))/2)
+	best.Left, best.Top = (sw-w)/2+int(g.Pan[0]), (sh-h)/2+int(g.Pan[1])
+	g.Grid = best
+	return sw, sh
+}
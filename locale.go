@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bukind/seabattle2/locales"
+)
+
+// Lang is the language of the texts shown to the player.
+// The texts are written in English in the code, and translated by the catalog.
+type Lang struct {
+	Code    string           `json:"-"` // like "en", the name of the file.
+	Name    string           `json:"name"`
+	Columns string           `json:"columns"` // the letters of the columns of the board.
+	Texts   map[string]Forms `json:"texts"`   // the translations of the English texts.
+	// Lookalikes are the letters of the other alphabets typed for the columns they look like,
+	// like the Latin B for the Cyrillic В.
+	Lookalikes map[string]string `json:"lookalikes"`
+
+	columns    []rune
+	lookalikes map[rune]rune
+	plural     func(n int) int
+}
+
+// Forms are the translation of the text, with a form for every plural category of the language.
+// The translation without the plural forms may be written as a single string.
+type Forms []string
+
+var (
+	// pluralRules choose the form of the text for the number, like in English by default.
+	pluralRules = map[string]func(n int) int{
+		"ru": func(n int) int {
+			switch {
+			case n%10 == 1 && n%100 != 11:
+				return 0
+			case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+				return 1
+			}
+			return 2
+		},
+	}
+
+	// langList is sorted by the code, English goes first.
+	langList = builtinLangs()
+)
+
+func (f *Forms) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*f = Forms{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(f))
+}
+
+func englishPlural(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+// builtinLangs returns the languages embedded into the game.
+func builtinLangs() []*Lang {
+	names, err := fs.Glob(locales.Files, "*.json")
+	if err != nil {
+		panic(err)
+	}
+	var list []*Lang
+	for _, name := range names {
+		b, err := locales.Files.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		l := &Lang{Code: strings.TrimSuffix(name, path.Ext(name))}
+		if err := json.Unmarshal(b, l); err != nil {
+			panic(fmt.Sprintf("language %s: %v", name, err))
+		}
+		if l.columns = []rune(l.Columns); len(l.columns) != Ncells {
+			panic(fmt.Sprintf("language %s: want %d columns, got %q", name, Ncells, l.Columns))
+		}
+		l.lookalikes = make(map[rune]rune)
+		for from, to := range l.Lookalikes {
+			f, t := []rune(from), []rune(to)
+			if len(f) != 1 || len(t) != 1 || !slices.Contains(l.columns, t[0]) {
+				panic(fmt.Sprintf("language %s: bad lookalike %q for %q", name, from, to))
+			}
+			l.lookalikes[f[0]] = t[0]
+		}
+		l.plural = pluralRules[l.Code]
+		if l.plural == nil {
+			l.plural = englishPlural
+		}
+		list = append(list, l)
+	}
+	return list
+}
+
+// findLang returns the language by its code, or English if there is none.
+func findLang(code string) *Lang {
+	for _, l := range langList {
+		if l.Code == code {
+			return l
+		}
+	}
+	return langList[0]
+}
+
+// systemLang returns the language of the environment, like "ru" for LANG=ru_RU.UTF-8.
+func systemLang() string {
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if s := os.Getenv(v); s != "" {
+			code, _, _ := strings.Cut(s, "_")
+			return strings.ToLower(code)
+		}
+	}
+	return ""
+}
+
+// tr returns the translation of the English text, formatted like fmt.Sprintf.
+func (l *Lang) tr(text string, args ...any) string {
+	if f := l.Texts[text]; len(f) > 0 {
+		text = f[0]
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// trn returns the translation of the English text with the form for the number n.
+// The English text has the form for one and for the rest.
+func (l *Lang) trn(n int, one, other string, args ...any) string {
+	forms := l.Texts[one]
+	if len(forms) == 0 {
+		forms, n = Forms{one, other}, englishPlural(n)
+	} else {
+		n = min(l.plural(n), len(forms)-1)
+	}
+	return fmt.Sprintf(forms[n], args...)
+}
+
+// cell returns the name of the cell with the letter of the column in the language, like "C5".
+func (l *Lang) cell(xy XY) string {
+	return fmt.Sprintf("%c%d", l.columns[xy.X], xy.Y+1)
+}
+
+// parseCell parses the cell named in the language. The letters of the other alphabets
+// are only taken for the columns they look like, never by their own order,
+// as the Latin E is the 5th column, but the Cyrillic Е is the 6th.
+func (l *Lang) parseCell(s string) (XY, error) {
+	r := []rune(strings.ToUpper(strings.TrimSpace(s)))
+	if len(r) == 2 {
+		if c, ok := l.lookalikes[r[0]]; ok {
+			r[0] = c
+		}
+		if x := slices.Index(l.columns, r[0]); x >= 0 {
+			if xy := (XY{x, int(r[1] - '1')}); inBoard(xy) {
+				return xy, nil
+			}
+		}
+	}
+	return XY{}, errors.New(l.tr("Bad cell %s, type a letter from %c to %c and a digit from 1 to %d",
+		string(r), l.columns[0], l.columns[Ncells-1], Ncells))
+}
+
+// tr translates the text to the language of the player, see Lang.tr.
+func (g *Game) tr(text string, args ...any) string {
+	return g.Lang.tr(text, args...)
+}
+
+// trn translates the text with the plural forms, see Lang.trn.
+func (g *Game) trn(n int, one, other string, args ...any) string {
+	return g.Lang.trn(n, one, other, args...)
+}
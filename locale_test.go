@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestParseCell(t *testing.T) {
+	en, ru := findLang("en"), findLang("ru")
+	for _, tc := range []struct {
+		lang *Lang
+		in   string
+		want XY
+		ok   bool
+	}{
+		{en, "C5", XY{2, 4}, true},
+		{en, " c5 ", XY{2, 4}, true},
+		{en, "H8", XY{7, 7}, true},
+		{en, "В5", XY{}, false}, // Cyrillic.
+		{en, "I1", XY{}, false},
+		{en, "A9", XY{}, false},
+		{en, "A0", XY{}, false},
+		{en, "C", XY{}, false},
+		{en, "C55", XY{}, false},
+		{en, "5C", XY{}, false},
+		{en, "", XY{}, false},
+		{ru, "В5", XY{2, 4}, true},
+		{ru, "в5", XY{2, 4}, true},
+		{ru, "З8", XY{7, 7}, true},
+		{ru, "Е1", XY{5, 0}, true},
+		// The Latin letters are the Cyrillic ones they look like, not the Latin columns.
+		{ru, "B5", XY{2, 4}, true},
+		{ru, "e1", XY{5, 0}, true},
+		{ru, "A1", XY{0, 0}, true},
+		{ru, "C5", XY{}, false},
+		{ru, "H8", XY{}, false},
+		{ru, "И1", XY{}, false},
+		{ru, "А9", XY{}, false},
+		{ru, "??", XY{}, false},
+	} {
+		got, err := tc.lang.parseCell(tc.in)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("%s parseCell(%q) = %v, %v, want %v, ok %v", tc.lang.Code, tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCellRoundTrip(t *testing.T) {
+	for _, l := range langList {
+		for y := 0; y < Ncells; y++ {
+			for x := 0; x < Ncells; x++ {
+				xy := XY{x, y}
+				if got, err := l.parseCell(l.cell(xy)); err != nil || got != xy {
+					t.Errorf("%s parseCell(%q) = %v, %v", l.Code, l.cell(xy), got, err)
+				}
+			}
+		}
+	}
+}
+
+func TestTrnPlurals(t *testing.T) {
+	ru := findLang("ru")
+	const one, other = "%d shot", "%d shots"
+	for _, tc := range []struct {
+		n      int
+		en, ru string
+	}{
+		{0, "0 shots", "0 выстрелов"},
+		{1, "1 shot", "1 выстрел"},
+		{2, "2 shots", "2 выстрела"},
+		{4, "4 shots", "4 выстрела"},
+		{5, "5 shots", "5 выстрелов"},
+		{11, "11 shots", "11 выстрелов"},
+		{12, "12 shots", "12 выстрелов"},
+		{21, "21 shots", "21 выстрел"},
+		{22, "22 shots", "22 выстрела"},
+		{111, "111 shots", "111 выстрелов"},
+	} {
+		if got := findLang("en").trn(tc.n, one, other, tc.n); got != tc.en {
+			t.Errorf("en trn(%d) = %q, want %q", tc.n, got, tc.en)
+		}
+		if got := ru.trn(tc.n, one, other, tc.n); got != tc.ru {
+			t.Errorf("ru trn(%d) = %q, want %q", tc.n, got, tc.ru)
+		}
+	}
+}
+
+func TestTrFallsBackToEnglish(t *testing.T) {
+	if got := findLang("ru").tr("no such text %d", 3); got != "no such text 3" {
+		t.Errorf("got %q", got)
+	}
+	if got := findLang("xx"); got.Code != "en" {
+		t.Errorf("findLang(xx) = %s, want en", got.Code)
+	}
+}
@@ -0,0 +1,11 @@
+// locales embed the translations of the game into Go.
+package locales
+
+import (
+	"embed"
+)
+
+var (
+	//go:embed *.json
+	Files embed.FS
+)
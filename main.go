@@ -1,15 +1,19 @@
 package main
 
 import (
-	"bytes"
+	"cmp"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"log"
 	"math/rand"
-	"slices"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/bukind/seabattle2/fonts"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
@@ -24,10 +28,13 @@ type Cell int
 
 type Side int
 
-type CellParams struct {
-	SceneColor   color.RGBA
-	CircleColor  color.RGBA
-	CircleRadius float32
+// Rules are the optional game rules.
+type Rules struct {
+	Weapons    bool // special weapons mode.
+	Mines      int  // number of mines placed by every player.
+	MineDamage bool // hitting a mine damages own ship instead of losing the turn.
+	Islands    int  // number of islands on every board.
+	Moving     bool // ships may move instead of firing.
 }
 
 const (
@@ -43,12 +50,14 @@ const (
 const (
 	CellEmpty Cell = iota
 	CellMiss       // empty cell being hit
-	CellMist       // mist -- empty cell hidden by the mist
-	CellHide       // hidden ship
+	CellMist       // mist -- unknown cell, only in the views of the opponent
 	CellShip       // ship cell
 	CellFire       // ship on fire
 	CellSunk       // sunk ship
-	CellOily       // hidden mark around sunk ship, also are used for placement
+	CellOily       // known empty cell around sunk ship in the view, also are used for placement
+	CellMine       // mine
+	CellBlast      // exploded mine
+	CellRock       // island, blocks placement and shots
 )
 
 const (
@@ -60,21 +69,6 @@ var (
 	ptSansFontSource *text.GoTextFaceSource
 
 	colorEmpty = color.RGBA{}
-	colorMist  = color.RGBA{0xbb, 0xbb, 0xbb, 0xff}
-	colorSea   = color.RGBA{0x00, 0x22, 0xff, 0xff}
-	colorShip  = color.RGBA{0x44, 0x44, 0x44, 0xff}
-	colorDead  = color.RGBA{0x22, 0x22, 0x22, 0xff}
-
-	cellParams = map[Cell]CellParams{
-		CellEmpty: {colorSea, colorEmpty, 0.},
-		CellMiss:  {colorSea, colorMist, 0.25},
-		CellMist:  {colorMist, colorEmpty, 0.},
-		CellHide:  {colorMist, colorEmpty, 0.},
-		CellShip:  {colorShip, colorEmpty, 0.},
-		CellFire:  {color.RGBA{0x88, 0x22, 0x22, 0xff}, color.RGBA{0xff, 0x88, 0x22, 0x88}, 0.45},
-		CellSunk:  {colorSea, colorDead, 0.55},
-		CellOily:  {colorSea, colorEmpty, 0.},
-	}
 
 	fillImage = func() *ebiten.Image {
 		img := ebiten.NewImageWithOptions(image.Rect(-1, -1, 1, 1), nil)
@@ -90,6 +84,10 @@ func colorS(c color.RGBA) string {
 	return fmt.Sprintf("#%x%x%x%x", c.R, c.G, c.B, c.A)
 }
 
+func (s Side) opponent() Side {
+	return 1 - s
+}
+
 func setVtxColor(v *ebiten.Vertex, c color.RGBA) {
 	v.ColorR = float32(c.R) / 0xff
 	v.ColorG = float32(c.G) / 0xff
@@ -105,8 +103,22 @@ func (xy XY) String() string {
 	return fmt.Sprintf("%c%c", 'A'+xy.X, '1'+xy.Y)
 }
 
+// parseXY parses the cell written like "C5", as XY.String does.
+func parseXY(s string) (XY, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) != 2 {
+		return XY{}, fmt.Errorf("bad cell %q, want a letter and a digit like C5", s)
+	}
+	xy := XY{int(s[0]) - 'A', int(s[1]) - '1'}
+	if !inBoard(xy) {
+		return XY{}, fmt.Errorf("bad cell %q, want A-%c and 1-%c", s, 'A'+Ncells-1, '1'+Ncells-1)
+	}
+	return xy, nil
+}
+
 func (g *Game) drawCursor(screen *ebiten.Image) {
-	col := color.RGBA{0, 0xff, 0, uint8(0xff * (g.Tick % (gameTPS + 1)) / gameTPS)}
+	col := color.RGBA(g.Theme.Cursor)
+	col.A = uint8(int64(col.A) * (g.Tick % (gameTPS + 1)) / gameTPS)
 
 	var path vector.Path
 	hw := cellSizeF / 2
@@ -128,28 +140,82 @@ func (g *Game) drawCursor(screen *ebiten.Image) {
 		AntiAlias: true,
 		FillRule:  ebiten.FillRuleNonZero,
 	})
-	g.opts.GeoM.Reset()
+	side, area := g.cursorArea()
+	for _, xy := range area {
+		g.opts.GeoM.Reset()
+		g.moveXY(&g.opts.GeoM, xy.X, xy.Y, side)
+		screen.DrawImage(g.cellImage, &g.opts)
+	}
+}
+
+// cursorArea returns the board side and the cells under the cursor of whoever is playing.
+// With weapons, these are all the cells affected by the weapon.
+func (g *Game) cursorArea() (Side, []XY) {
+	if g.PlacingMines {
+		return SideSelf, []XY{g.CursorOwn}
+	}
+	if g.Moving && g.WhoseTurn == SideSelf {
+		if g.MovingShip != nil {
+			return SideSelf, g.MovingShip.Cells
+		}
+		return SideSelf, []XY{g.CursorOwn}
+	}
 	cursor := g.CursorSelf
+	weapon := g.Arsenals[SideSelf].Selected
 	side := SidePeer
 	if g.WhoseTurn != SideSelf {
 		cursor = g.CursorPeer
+		weapon = g.PeerWeapon
 		side = SideSelf
 	}
-	g.moveXY(&g.opts.GeoM, cursor.X, cursor.Y, side)
-	screen.DrawImage(g.cellImage, &g.opts)
+	if !g.Rules.Weapons {
+		weapon = WeaponShot
+	}
+	return side, weaponArea(weapon, side.opponent(), cursor)
 }
 
 type Game struct {
-	Tick        int64
-	LastUpdate  int64 // the tick when was the last update on the board.
-	Boards      [2]*Board
-	WhoseTurn   Side
-	Message     string
-	Error       error // terminating error.
-	CursorSelf  XY
-	CursorPeer  XY
-	PeerToHit   XY // Where is the spot peer wants to hit.
-	LastPeerHit XY // The successful one.
+	Rules        Rules
+	Tick         int64
+	LastUpdate   int64 // the tick when was the last update on the board.
+	Boards       [2]*Board
+	WhoseTurn    Side
+	Message      string
+	Error        error // terminating error.
+	CursorSelf   XY
+	CursorPeer   XY
+	CursorOwn    XY           // Cursor on own board while moving a ship or hiding the mines.
+	Moving       bool         // Whether the player is moving a ship.
+	MovingShip   *Ship        // The ship selected to move.
+	PlacingMines bool         // Whether the player hides the mines on own board before the first turn.
+	Skips        [2]bool      // Whether the side loses its next turn.
+	PeerToHit    XY           // Where is the spot peer wants to hit.
+	PeerWeapon   Weapon       // What peer wants to hit with.
+	Peer         Strategy     // Who plays the peer.
+	Thinking     chan PeerAct // The action being chosen by the peer, if any.
+	Arsenals     [2]*Arsenal
+	Rand         *rand.Rand // Random source for placement and mines.
+	History      []Turn
+	Events       []string   // The shots told in the language of the player, for the status panel.
+	Grid         Grid       // Where the boards are on the screen.
+	Zoom         float64    // The boards zoomed by the pinch, 1 when they fit the window.
+	Pan          [2]float64 // The zoomed boards dragged by the pinch.
+	ConfirmTaps  bool       // Whether the tap only selects the cell, and the second tap fires.
+	TapArmed     bool       // Whether the cell under the cursor is selected by the tap.
+	Chat         *Chat      // The chat with the peer, if the peer can talk.
+	Undo         []Choice   // The choices of the player in this turn, the last is undone first.
+	Settings     *Settings
+	Keymap       Keymap // The key bindings by the settings.
+	Menu         *Menu  // The settings screen, if shown.
+	HideCoords   bool   // Whether the letters and the numbers are hidden.
+	ShowOrder    bool   // Whether all the shots are numbered by their turns, not only the recent ones.
+	Theme        *Theme // The look by the settings.
+	Lang         *Lang  // The language of the texts by the settings.
+	Animate      bool   // Whether the shots are animated.
+	Effects      []*Effect
+	Audio        *Audio     // The sounds, only in the window.
+	Announcer    *Announcer // Tells what happens, if anyone listens.
+	Target       []rune     // The cell being typed, if any.
 
 	// cache objects.
 	cellImage     *ebiten.Image
@@ -159,24 +225,49 @@ type Game struct {
 	keys          []ebiten.Key
 	activeTouches []ebiten.TouchID
 	killedTouches []ebiten.TouchID
+	spentTouches  []ebiten.TouchID // made gestures, so they do not fire.
+	pinch         *Pinch
+	gamepads      []ebiten.GamepadID
+	gamepadHeld   [4]int // ticks the gamepad directions are held.
+}
+
+// PeerAct is the action chosen by the peer strategy.
+type PeerAct struct {
+	Action Action
+	Err    error
 }
 
-func NewGame() *Game {
+// NewGame returns the game against the peer strategy.
+// The peer is nil when the strategies are given the turns by the caller, like in the tournament.
+func NewGame(rules Rules, peer Strategy) *Game {
 	g := &Game{
-		Message:   "Note: ships can only touch by corners",
-		cellImage: ebiten.NewImage(cellSize, cellSize),
+		Rules: rules,
+		Rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		Peer:  peer,
 	}
+	g.setSettings(defaultSettings())
 	g.Boards = [2]*Board{NewBoard(g, SideSelf), NewBoard(g, SidePeer)}
+	g.Arsenals = [2]*Arsenal{NewArsenal(), NewArsenal()}
 	return g
 }
 
+// setSettings applies the settings of the player.
+func (g *Game) setSettings(s *Settings) {
+	g.Settings = s
+	g.Keymap = s.keymap()
+	g.Theme = findTheme(s.Theme)
+	g.Lang = findLang(s.Lang)
+}
+
 func (g *Game) init() error {
-	for _, b := range g.Boards {
-		if err := b.addRandomShips(30); err != nil {
-			return err
-		}
+	g.Message = g.tr("Note: ships can only touch by corners")
+	if err := g.Boards[SideSelf].setup(30, Shipyard.AddRandomShips); err != nil {
+		return err
 	}
-	return nil
+	if g.PlacingMines {
+		g.minesHidden()
+	}
+	return g.Boards[SidePeer].setup(30, g.Peer.Place)
 }
 
 func (g *Game) Update() error {
@@ -186,30 +277,70 @@ func (g *Game) Update() error {
 		}
 	}
 	g.Tick++
-	if g.Error != nil {
+	g.updateEffects()
+	g.updateAudio()
+	g.flushAnnouncements()
+	if g.Error != nil && !g.animating() && !g.Audio.busy() {
+		g.finish()
 		return ebiten.Termination
 	}
+	if g.Error != nil {
+		return nil
+	}
 	if err := g.update(); err != nil {
 		g.Error = err
+		g.playEnding()
 	}
 	return nil
 }
 
 func (g *Game) update() error {
+	defer g.remember(g.choice())
+	if err := g.handleTouches(); err != nil {
+		return err
+	}
+	typing := false
+	if g.Chat != nil && g.Menu == nil && g.Target == nil {
+		typing = g.Chat.Typing
+		if status := g.Chat.update(g); status != "" {
+			g.Message = status
+		}
+	}
+	if g.animating() {
+		// Nothing happens until the last shot is seen.
+		return nil
+	}
+	if typing {
+		// The keys are typed into the chat, while the game goes on.
+		return g.updatePeer()
+	}
 	if err := g.handleKeys(); err != nil {
 		return err
 	}
-	if err := g.handleTouches(); err != nil {
+	if err := g.handleGamepads(); err != nil {
 		return err
 	}
 	if err := g.handleMouse(); err != nil {
 		return err
 	}
+	return g.updatePeer()
+}
 
-	// Handle peer activity.
+// updatePeer handles peer activity.
+func (g *Game) updatePeer() error {
 	if g.WhoseTurn == SideSelf || g.Tick-g.LastUpdate < peerTicksPerAct {
 		return nil
 	}
+	if g.Thinking != nil {
+		select {
+		case r := <-g.Thinking:
+			g.Thinking = nil
+			return g.peerChosen(r.Action, r.Err)
+		default:
+			// The game goes on while the peer thinks.
+			return nil
+		}
+	}
 	g.LastUpdate = g.Tick
 	if g.CursorPeer != g.PeerToHit {
 		// moving peer cursor
@@ -217,17 +348,29 @@ func (g *Game) update() error {
 		g.CursorPeer.Y += sign(g.PeerToHit.Y - g.CursorPeer.Y)
 		return nil
 	}
-	if g.Boards[SideSelf].hitCell(g.PeerToHit) {
-		g.LastPeerHit = g.PeerToHit
-		if g.Boards[SideSelf].Lives == 0 {
-			// The last ship is dead!
-			return fmt.Errorf("The peer has won the game!")
-		}
-		if err := g.peerToHit(); err != nil {
-			return err
-		}
-	} else {
-		g.WhoseTurn = SideSelf
+	again, err := g.shoot(SidePeer, g.PeerWeapon, g.PeerToHit)
+	if err != nil {
+		return fmt.Errorf("peer: %w", err)
+	}
+	g.observePeer(true, Action{Weapon: g.PeerWeapon, Target: g.PeerToHit})
+	if err := g.checkWinner(); err != nil {
+		return err
+	}
+	if again || g.nextTurn(SidePeer) == SidePeer {
+		return g.peerToHit()
+	}
+	g.WhoseTurn = SideSelf
+	return nil
+}
+
+// checkWinner returns the terminating error if any side has no ships left.
+func (g *Game) checkWinner() error {
+	if g.Boards[SidePeer].Lives == 0 {
+		return errors.New(g.tr("You have won the game!"))
+	}
+	if g.Boards[SideSelf].Lives == 0 {
+		// The last ship is dead!
+		return errors.New(g.tr("The peer has won the game!"))
 	}
 	return nil
 }
@@ -241,313 +384,160 @@ func sign(v int) int {
 	return 0
 }
 
-func (g *Game) handleKeys() error {
-	g.keys = inpututil.AppendJustReleasedKeys(g.keys[:0])
-	for _, k := range g.keys {
-		if k == ebiten.KeyQ {
-			// TODO: remove this.
-			// Special handling even during peer turn.
-			return fmt.Errorf("Stopped by player")
-		}
-		if g.WhoseTurn == SidePeer {
-			continue
-		}
-		switch k {
-		case ebiten.KeyArrowUp:
-			g.CursorSelf.Y--
-			if g.CursorSelf.Y < 0 {
-				g.CursorSelf.Y = Ncells - 1
-			}
-		case ebiten.KeyArrowDown:
-			g.CursorSelf.Y++
-			if g.CursorSelf.Y >= Ncells {
-				g.CursorSelf.Y = 0
-			}
-		case ebiten.KeyArrowLeft:
-			g.CursorSelf.X--
-			if g.CursorSelf.X < 0 {
-				g.CursorSelf.X = Ncells - 1
-			}
-		case ebiten.KeyArrowRight:
-			g.CursorSelf.X++
-			if g.CursorSelf.X >= Ncells {
-				g.CursorSelf.X = 0
-			}
-		case ebiten.KeySpace:
-			if g.Boards[SidePeer].hitCell(g.CursorSelf) {
-				if g.Boards[SidePeer].Lives == 0 {
-					return fmt.Errorf("You have won the game!")
-				}
-			} else {
-				if err := g.peerToHit(); err != nil {
-					return err
-				}
-			}
-		}
+// selfShoot fires the selected weapon of the player at the cursor.
+func (g *Game) selfShoot() error {
+	w := g.Arsenals[SideSelf].Selected
+	again, err := g.shoot(SideSelf, w, g.CursorSelf)
+	if err != nil {
+		g.Message = g.Arsenals[SideSelf].status(g.Lang, w)
+		return nil
 	}
-	return nil
-}
-
-func (g *Game) handleTouches() error {
-	g.activeTouches = inpututil.AppendJustPressedTouchIDs(g.activeTouches)
-	slices.Sort(g.activeTouches)
-	g.killedTouches = inpututil.AppendJustReleasedTouchIDs(g.killedTouches[:0])
-	slices.Sort(g.killedTouches)
-	// Remove killedTouches from activeTouches
-	i, j := 0, 0
-	for k := 0; k < len(g.killedTouches) && i < len(g.activeTouches); i++ {
-		if g.activeTouches[i] == g.killedTouches[k] {
-			// if the touch is released.
-			k++
-		} else {
-			// the touch is not released yet.
-			g.activeTouches[j] = g.activeTouches[i]
-			j++
-		}
+	g.observePeer(false, Action{Weapon: w, Target: g.CursorSelf})
+	if err := g.checkWinner(); err != nil {
+		return err
 	}
-	g.activeTouches = append(g.activeTouches[:j], g.activeTouches[i:]...)
-	if g.WhoseTurn != SideSelf {
+	if again {
 		return nil
 	}
-	// Draw cursor at the active touches.
-	for _, t := range g.activeTouches {
-		// TODO: if touch is outside the board, do not hit it.
-		tx, ty := ebiten.TouchPosition(t)
-		g.CursorSelf.X = pos2Cell(tx, Ncells+1, Ncells)
-		g.CursorSelf.Y = pos2Cell(ty, 1, Ncells)
-	}
-	for _, t := range g.killedTouches {
-		// TODO: if touch is outside the board, do not hit it.
-		tx, ty := inpututil.TouchPositionInPreviousTick(t)
-		g.CursorSelf.X = pos2Cell(tx, Ncells+1, Ncells)
-		g.CursorSelf.Y = pos2Cell(ty, 1, Ncells)
-		if g.Boards[SidePeer].hitCell(g.CursorSelf) {
-			if g.Boards[SidePeer].Lives == 0 {
-				return fmt.Errorf("You have won the game!")
-			}
-		} else {
-			return g.peerToHit()
-		}
+	return g.endSelfTurn()
+}
+
+// endSelfTurn passes the turn to the peer, unless the peer has to skip it.
+func (g *Game) endSelfTurn() error {
+	if g.nextTurn(SideSelf) == SideSelf {
+		return nil
 	}
-	return nil
+	return g.peerToHit()
+}
+
+// nextTurn ends the turn of the side and returns who plays the next one: the opponent,
+// or the side again if the opponent has to skip its turn. Every turn, even the skipped one,
+// cools the weapons of its side down.
+func (g *Game) nextTurn(side Side) Side {
+	next := side.opponent()
+	g.Arsenals[next].cool()
+	if g.Skips[next] {
+		g.Skips[next] = false
+		next = side
+		g.Arsenals[next].cool()
+	}
+	return next
 }
 
 func (g *Game) handleMouse() error {
-	if g.WhoseTurn != SideSelf {
+	if g.WhoseTurn != SideSelf || g.Menu != nil {
 		return nil
 	}
 	cx, cy := ebiten.CursorPosition()
 	pressed := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
 	justReleased := inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft)
+	if g.PlacingMines {
+		if justReleased && g.Grid.inBoard(cx, cy, SideSelf) {
+			g.hideMine(g.Grid.pos2Cell(cx, cy, SideSelf))
+		}
+		return nil
+	}
+	if g.Moving {
+		// Only own board is clickable.
+		if justReleased && g.Grid.inBoard(cx, cy, SideSelf) {
+			return g.handleMoveClick(g.Grid.pos2Cell(cx, cy, SideSelf))
+		}
+		return nil
+	}
+	if w, ok := g.weaponAt(cx, cy); ok && g.Rules.Weapons {
+		if justReleased {
+			g.selectWeapon(w)
+		}
+		return nil
+	}
+	if !g.Grid.inBoard(cx, cy, SidePeer) {
+		// The clicks around the board, like on the margins, are not shots.
+		return nil
+	}
 	if pressed || justReleased {
 		// Draw game cursor.
-		g.CursorSelf.X = pos2Cell(cx, Ncells+1, Ncells)
-		g.CursorSelf.Y = pos2Cell(cy, 1, Ncells)
+		g.CursorSelf = g.Grid.pos2Cell(cx, cy, SidePeer)
 	}
 	if justReleased {
-		if g.Boards[SidePeer].hitCell(g.CursorSelf) {
-			if g.Boards[SidePeer].Lives == 0 {
-				return fmt.Errorf("You have won the game!")
-			}
-		} else {
-			return g.peerToHit()
-		}
+		return g.selfShoot()
 	}
 	return nil
 }
 
-// peerToHit is to find where peer wants to hit.
+// peerToHit asks the peer strategy for its action in the background,
+// as the bot may take a while. The action is picked up by updatePeer.
 func (g *Game) peerToHit() error {
 	g.WhoseTurn = SidePeer
 	g.LastUpdate = g.Tick
-	if g.Boards[SideSelf].Cells[g.LastPeerHit.Y][g.LastPeerHit.X] == CellFire {
-		xys := g.peerToHitShipMore()
-		if len(xys) == 0 {
-			return fmt.Errorf("cannot find next hit point after %s", g.LastPeerHit)
-		}
-		g.PeerToHit = xys[rand.Intn(len(xys))]
-		return nil
-	}
-	return g.huntLargestStrategy()
-	// return g.uniformStrategy()
-}
-
-// uniformStrategy uniformly choose a random cell to hit.
-func (g *Game) uniformStrategy() error {
-	// The previous attempt was a miss, or the ship was sunk.
-	g.PeerToHit.X = rand.Intn(Ncells)
-	g.PeerToHit.Y = rand.Intn(Ncells)
-	for i := 0; i < Ncells*Ncells; i++ {
-		c := g.Boards[SideSelf].Cells[g.PeerToHit.Y][g.PeerToHit.X]
-		if c == CellEmpty || c == CellShip {
-			return nil
-		}
-		// check the next cell
-		g.PeerToHit.X++
-		if g.PeerToHit.X >= Ncells {
-			g.PeerToHit.X = 0
-			g.PeerToHit.Y++
-			if g.PeerToHit.Y >= Ncells {
-				g.PeerToHit.Y = 0
-			}
-		}
-	}
-	// All cells are not suitable!
-	return fmt.Errorf("all cells are hit already")
+	own := g.Boards[SidePeer]
+	// The peer only knows what is visible through the mist.
+	mine, theirs, a := own.view(SidePeer), g.Boards[SideSelf].view(SidePeer), *g.Arsenals[SidePeer]
+	thinking := make(chan PeerAct, 1)
+	go func() {
+		act, err := g.Peer.Act(mine, theirs, &a, own.canMoveAt)
+		thinking <- PeerAct{act, err}
+	}()
+	g.Thinking = thinking
+	return nil
 }
 
-// huntLargestStrategy hunts for the largest ship.
-func (g *Game) huntLargestStrategy() error {
-	largest := maxShipSize
-	b := g.Boards[SideSelf]
-	for ; largest > 0; largest-- {
-		if b.Ships[largest-1] > 0 {
-			break
-		}
-	}
-	if largest <= 0 {
-		return fmt.Errorf("could not determine largest ship")
-	}
-	cellWeight := make(map[XY]int)
-	markSlice := func(i0, i1 int, f func(i, w int)) {
-		if i0 == -1 {
-			return
-		}
-		if i1-i0 < largest {
-			return
-		}
-		// 0123456 <-- indices
-		// 123321  <-- weight
-		// log.Printf("markSlice(%d, %d)", i0, i1)
-		for i := i0; i < i1; i++ {
-			w := largest
-			if w1 := i - i0 + 1; w1 < w {
-				w = w1
-			}
-			if w2 := i1 - i; w2 < w {
-				w = w2
-			}
-			f(i, w)
-		}
+// peerChosen does the action chosen by the peer strategy.
+// The move is done at once, while the shot waits for the peer cursor to reach the target.
+func (g *Game) peerChosen(act Action, err error) error {
+	if err != nil {
+		return fmt.Errorf("peer: %w", err)
 	}
-	// Scan along X.
-	for y := 0; y < Ncells; y++ {
-		xStart := -1
-		x := 0
-		fx := func(i, w int) {
-			xy := XY{i, y}
-			w0 := cellWeight[xy]
-			cellWeight[xy] = w0 + w
-			// log.Printf("weight %s: %d + %d -> %d", xy, w0, w, cellWeight[xy])
+	if act.Move {
+		if err := g.strategyMove(SidePeer, act); err != nil {
+			return fmt.Errorf("peer: %w", err)
 		}
-		for ; x < Ncells; x++ {
-			switch c := b.Cells[y][x]; c {
-			case CellEmpty, CellShip:
-				if xStart == -1 {
-					xStart = x
-				}
-			default:
-				markSlice(xStart, x, fx)
-				xStart = -1
-			}
-		}
-		markSlice(xStart, x, fx)
-	}
-	// Scan along Y.
-	for x := 0; x < Ncells; x++ {
-		yStart := -1
-		y := 0
-		fy := func(i, w int) {
-			xy := XY{x, i}
-			w0 := cellWeight[xy]
-			cellWeight[xy] = w0 + w
-			// log.Printf("weight %s: %d + %d -> %d", xy, w0, w, cellWeight[xy])
-		}
-		for ; y < Ncells; y++ {
-			switch c := b.Cells[y][x]; c {
-			case CellEmpty, CellShip:
-				if yStart == -1 {
-					yStart = y
-				}
-			default:
-				markSlice(yStart, y, fy)
-				yStart = -1
-			}
-		}
-		markSlice(yStart, y, fy)
-	}
-	// Find cells with max count in the map.
-	maxW := 0
-	var cells []XY
-	for xy, w := range cellWeight {
-		if w < maxW {
-			continue
-		}
-		if w > maxW {
-			cells = cells[:0]
-			maxW = w
+		g.Message = g.tr("The peer has moved a ship")
+		g.observePeer(true, act)
+		if g.nextTurn(SidePeer) == SidePeer {
+			return g.peerToHit()
 		}
-		cells = append(cells, xy)
+		g.WhoseTurn = SideSelf
+		return nil
 	}
-	// log.Printf("total suitable cells: %d; maxW(%d) cells(%d): %v", len(cellWeight), maxW, len(cells), cells)
-	if len(cells) == 0 {
-		return fmt.Errorf("cannot find cells for the largest ship")
+	if err := g.checkShot(SidePeer, act); err != nil {
+		return fmt.Errorf("peer: %w", err)
 	}
-	i := rand.Intn(len(cells))
-	g.PeerToHit = cells[i]
+	g.PeerToHit = act.Target
+	g.PeerWeapon = act.Weapon
 	return nil
 }
 
-func (g *Game) peerToHitShipMore() []XY {
-	b := g.Boards[SideSelf]
-	only := false
-	check := func(xys *[]XY, xy XY) bool {
-		switch c := b.Cells[xy.Y][xy.X]; c {
-		case CellFire:
-			only = true
-			return true
-		case CellEmpty, CellShip:
-			*xys = append(*xys, xy)
-		}
-		return false
-	}
-	xs := make([]XY, 0, 4)
-	for dx := -1; dx < 2; dx += 2 {
-		for x := g.LastPeerHit.X + dx; x >= 0 && x < Ncells && check(&xs, XY{x, g.LastPeerHit.Y}); x += dx {
-		}
+// observePeer reports the boards to the peer strategy after the action of either side.
+func (g *Game) observePeer(own bool, act Action) {
+	g.Peer.Observe(own, act, g.Boards[SidePeer].view(SidePeer), g.Boards[SideSelf].view(SidePeer))
+}
+
+// finish tells the peer strategy how the game has ended.
+func (g *Game) finish() {
+	if g.Thinking != nil {
+		// The strategy is told the end only when it is done thinking.
+		<-g.Thinking
+		g.Thinking = nil
 	}
-	if only {
-		return xs
+	outcome := "stopped"
+	if len(g.Boards[SidePeer].Fleet) > 0 && g.Boards[SidePeer].Lives == 0 {
+		outcome = "lose"
+	} else if len(g.Boards[SideSelf].Fleet) > 0 && g.Boards[SideSelf].Lives == 0 {
+		outcome = "win"
 	}
-	ys := make([]XY, 0, 4)
-	for dy := -1; dy < 2; dy += 2 {
-		for y := g.LastPeerHit.Y + dy; y >= 0 && y < Ncells && check(&ys, XY{g.LastPeerHit.X, y}); y += dy {
-		}
+	if err := g.Peer.End(outcome); err != nil {
+		log.Printf("peer: %v", err)
 	}
-	if only {
-		return ys
-	}
-	return append(xs, ys...)
 }
 
 func cellPos(row int) int {
 	return cellBorder + (cellSize+cellBorder)*row
 }
 
-func pos2Cell(pos int, min, max int) int {
-	c := (pos-cellBorder)/(cellSize+cellBorder) - min
-	if c < 0 {
-		c = 0
-	}
-	if c >= max {
-		c = max - 1
-	}
-	return c
-}
-
 // moveXY translates GeoM into the cell (X,Y) coordinate of the cell on the board.
 func (g *Game) moveXY(m *ebiten.GeoM, x, y int, side Side) {
-	m.Translate(float64(cellPos(int(side)*(Ncells+1)+x)), float64(cellPos(y+1)))
+	px, py := g.Grid.origin(x, y, side)
+	m.Translate(float64(px), float64(py))
 }
 
 // textInXY returns text options for the text centered in cell (X,Y)
@@ -557,51 +547,245 @@ func (g *Game) textInXY(x, y int, side Side) *text.DrawOptions {
 	topts.SecondaryAlign = text.AlignCenter
 	topts.GeoM.Translate(cellSize*0.5, cellSize*0.5)
 	g.moveXY(&topts.GeoM, x, y, side)
+	topts.ColorScale.ScaleWithColor(color.RGBA(g.Theme.Text))
 	return topts
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	g.Boards[0].draw(screen)
-	g.Boards[1].draw(screen)
-	// Draw vertical numbers between boards.
-	for y := 0; y < Ncells; y++ {
-		text.Draw(screen, fmt.Sprintf("%c", '1'+y), &text.GoTextFace{
-			Source: ptSansFontSource,
-			Size:   cellSize * 0.8,
-		}, g.textInXY(Ncells, y, SideSelf))
+	if g.cellImage == nil {
+		// Only create it when drawing, other front-ends do not need it.
+		g.cellImage = ebiten.NewImage(cellSize, cellSize)
+	}
+	// The screen shakes by moving the grid while drawing.
+	dx, dy := g.shakeOffset()
+	g.Grid.Left += dx
+	g.Grid.Top += dy
+	defer func() {
+		g.Grid.Left -= dx
+		g.Grid.Top -= dy
+	}()
+	g.drawBackground(screen)
+	views := g.views()
+	for i, v := range views {
+		g.Boards[i].draw(screen, v)
+	}
+	g.drawEffects(screen, views)
+	g.drawShotMarks(screen)
+	if g.Rules.Weapons {
+		g.drawWeapons(screen)
 	}
+	g.drawNumbers(screen)
 	g.drawCursor(screen)
+	_, h := g.Grid.pixelSize(g.Rules.Weapons)
+	g.drawHUD(screen, g.Grid.Left, g.Grid.Top+h)
+	if g.Chat != nil {
+		g.Chat.draw(g, screen, g.Grid.Left, g.Grid.Top+h+hudHeight())
+	}
+	if g.Menu != nil {
+		g.Menu.draw(g, screen)
+	}
+	g.drawTooltip(screen)
 	msg := g.Message
+	if g.Target != nil {
+		msg = g.tr("Target: %s_", string(g.Target)) + "   " + g.Message
+	}
 	if g.Error != nil {
 		msg = g.Error.Error()
 	}
+	g.drawMessage(screen, msg)
+}
+
+// views returns the views of both boards as the player sees them.
+func (g *Game) views() [2]*View {
+	return [2]*View{g.Boards[SideSelf].view(SideSelf), g.Boards[SidePeer].view(SideSelf)}
+}
+
+// drawNumbers draws the row numbers between the boards, or right of them when stacked.
+func (g *Game) drawNumbers(screen *ebiten.Image) {
+	if g.HideCoords {
+		return
+	}
+	for side := SideSelf; side <= SidePeer; side++ {
+		if side == SidePeer && !g.Grid.Portrait {
+			break
+		}
+		for y := 0; y < Ncells; y++ {
+			text.Draw(screen, fmt.Sprintf("%c", '1'+y), g.Theme.face(cellSize*0.8), g.textInXY(Ncells, y, side))
+		}
+	}
+}
+
+// drawMessage draws the message above the boards.
+func (g *Game) drawMessage(screen *ebiten.Image, msg string) {
 	if msg != "" {
-		text.Draw(screen, msg, &text.GoTextFace{
-			Source: ptSansFontSource,
-			Size:   cellSize * 0.8,
-		}, g.textInXY(Ncells, -1, SideSelf))
+		topts := g.textInXY(0, -1, SideSelf)
+		w, _ := g.Grid.pixelSize(g.Rules.Weapons)
+		topts.GeoM.Translate(float64(w-cellSize)/2-cellBorder, 0)
+		text.Draw(screen, msg, g.Theme.face(cellSize*0.8), topts)
 	}
 }
 
 func (g *Game) Layout(oW, oH int) (int, int) {
-	return cellPos(Ncells*2 + 1), cellPos(Ncells + 2)
+	if g.Chat != nil {
+		return g.fit(oW, oH, hudHeight()+chatHeight())
+	}
+	return g.fit(oW, oH, hudHeight())
 }
 
 func loadFonts() {
-	s, err := text.NewGoTextFaceSource(bytes.NewReader(fonts.PTSansRegular))
-	if err != nil {
-		log.Fatal(err)
-	}
-	ptSansFontSource = s
+	ptSansFontSource = fontSource("sans")
 }
 
 func main() {
+	var rules Rules
+	flag.BoolVar(&rules.Weapons, "weapons", false, "play with special weapons: radar, torpedo and cluster bomb")
+	flag.IntVar(&rules.Mines, "mines", 0, "number of mines placed by every player")
+	flag.BoolVar(&rules.MineDamage, "mine-damage", false, "hitting a mine damages own ship instead of losing the turn")
+	flag.IntVar(&rules.Islands, "islands", 0, "number of islands on every board")
+	flag.BoolVar(&rules.Moving, "moving", false, "ships may move by one cell instead of firing")
+	tui := flag.Bool("tui", false, "play in the terminal instead of the window")
+	cli := flag.Bool("cli", false, "play by typing commands like C5 to stdin")
+	bot := flag.String("bot", "", "command running the bot to play as the peer, see PROTOCOL.md")
+	botTimeout := flag.Duration("bot-timeout", 10*time.Second, "how long to wait for every reply of the bot")
+	tournament := flag.Bool("tournament", false, "play the tournament between the strategies given as arguments: hunt, uniform or bot commands")
+	format := flag.String("format", "round-robin", "tournament format: round-robin or swiss")
+	games := flag.Int("games", 10, "tournament games per pairing")
+	rounds := flag.Int("rounds", 3, "rounds of the swiss tournament")
+	seed := flag.Int64("seed", 1, "seed of the first tournament game")
+	records := flag.String("records", "", "directory to save the most interesting tournament games to")
+	serve := flag.String("serve", "", "serve the HTTP API for playing at the address, like localhost:8080")
+	delay := flag.Duration("spectator-delay", 0, "delay of the broadcast to the spectators of the served games")
+	watch := flag.String("watch", "", "watch the served game at the URL, like http://localhost:8080/games/1")
+	view := flag.String("view", "neutral", "what to watch: neutral, you, peer or full")
+	name := flag.String("name", "", "your name in the chat, spectator when watching and player when playing by default")
+	confirmTaps := flag.Bool("confirm-taps", false, "tap a cell to select it and tap it again to fire")
+	speak := flag.Bool("speak", false, "tell what happens with the text-to-speech and play the audio cues, for the players who cannot see the board")
+	announce := flag.String("announce", "", "append what happens line by line to the file, like for a screen reader")
+	theme := flag.String("theme", "", "the look of the game, like colorblind or high-contrast, instead of the one in the settings")
+	lang := flag.String("lang", "", "the language of the game, like en or ru, instead of the one in the settings or the environment")
+	flag.Parse()
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	if *lang != "" && findLang(*lang).Code != *lang {
+		fmt.Fprintf(os.Stderr, "unknown language %q\n", *lang)
+		os.Exit(1)
+	}
+	if *serve != "" {
+		log.Fatal(runServer(*serve, *bot, *botTimeout, *delay))
+	}
+	if *watch != "" {
+		loadFonts()
+		ebiten.SetWindowSize(640, 480)
+		ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+		ebiten.SetWindowTitle("sea battle: watching")
+		ebiten.SetTPS(gameTPS)
+		if err := ebiten.RunGame(NewSpectator(*watch, *view, cmp.Or(*name, "spectator"), findLang(cmp.Or(*lang, systemLang())))); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *tournament {
+		log.SetOutput(io.Discard)
+		if err := runTournament(rules, flag.Args(), *format, *games, *rounds, *seed, *botTimeout, *records, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *tui || *cli {
+		// Keep the log away from the board.
+		log.SetOutput(io.Discard)
+	}
+	if dir, err := themesDir(); err == nil {
+		if err := loadThemes(dir); err != nil {
+			log.Print(err)
+		}
+	}
+	if *theme != "" && themeIndex(*theme) < 0 {
+		fmt.Fprintf(os.Stderr, "unknown theme %q\n", *theme)
+		os.Exit(1)
+	}
+	var peer Strategy = NewAIStrategy(rules)
+	if *bot != "" {
+		s, err := NewBotStrategy(*bot, rules, *botTimeout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		peer = s
+	}
+	g := NewGame(rules, peer)
+	g.ConfirmTaps = *confirmTaps
+	// The command line has no cursor, so its mines are hidden at random.
+	g.PlacingMines = rules.Mines > 0 && !*cli
+	if *theme != "" {
+		g.Theme = findTheme(*theme)
+	}
+	if *tui {
+		// The terminal speaks the language of the player,
+		// while the command line stays in English, like the commands it reads.
+		g.Lang = findLang(cmp.Or(*lang, systemLang()))
+	}
+	if !*cli {
+		// The command line tells it all anyway.
+		a, err := NewAnnouncer(*announce)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		g.Announcer = a
+		g.Settings.Speech = *speak
+		g.Settings.Cues = *speak
+	}
+	if *tui {
+		if err := runTUI(g); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *cli {
+		if err := runCLI(g, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if path, err := settingsPath(); err != nil {
+		log.Printf("Settings are not saved: %v", err)
+	} else {
+		s, err := loadSettings(path)
+		if err != nil {
+			log.Print(err)
+		}
+		if *theme != "" {
+			s.Theme = *theme
+		}
+		if *lang != "" {
+			s.Lang = *lang
+		} else if s.Lang == "" {
+			s.Lang = systemLang()
+		}
+		if *speak {
+			s.Speech, s.Cues = true, true
+		}
+		g.setSettings(s)
+	}
+	g.Animate = !g.Settings.NoAnimations
+	if bot, ok := peer.(*BotStrategy); ok {
+		// The bot may relay the chat of a remote player.
+		g.Chat = NewPeerChat(bot, cmp.Or(*name, "player"), bot.Name)
+	}
+	sound, err := NewAudio()
+	if err != nil {
+		log.Printf("No sound: %v", err)
+	}
+	g.Audio = sound
 	loadFonts()
 	ebiten.SetWindowSize(640, 480)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetWindowTitle("sea battle")
 	ebiten.SetTPS(gameTPS)
-	if err := ebiten.RunGame(NewGame()); err != nil {
+	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
 }
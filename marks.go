@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// recentMarks is the number of the last shots marked on every board.
+const recentMarks = 3
+
+// drawShotMarks frames the cells changed by the last shots at both boards, fading
+// with their age, and numbers them by their turns. With ShowOrder every shot is numbered.
+func (g *Game) drawShotMarks(screen *ebiten.Image) {
+	face := g.Theme.face(cellSize * 0.35)
+	for side := SideSelf; side <= SidePeer; side++ {
+		turns := g.shotTurns(side)
+		recent := g.recentShots(side, recentMarks)
+		for y := 0; y < Ncells; y++ {
+			for x := 0; x < Ncells; x++ {
+				t := turns[y][x]
+				age := slices.Index(recent, t)
+				if t == 0 || age < 0 && !g.ShowOrder {
+					continue
+				}
+				px, py := g.Grid.cellOrigin(x, y, side)
+				if age >= 0 {
+					col := color.RGBA(g.Theme.Recent)
+					col.A = uint8(int(col.A) * (recentMarks - age) / recentMarks)
+					vector.StrokeRect(screen, px+2, py+2, cellSize-4, cellSize-4, 2, col, false)
+				}
+				opts := &text.DrawOptions{}
+				opts.GeoM.Translate(float64(px)+3, float64(py)+1)
+				opts.ColorScale.ScaleWithColor(color.RGBA(g.Theme.Text))
+				text.Draw(screen, strconv.Itoa(t), face, opts)
+			}
+		}
+	}
+}
+
+// cellInfo describes the cell of the board as the player sees it,
+// with the turn it was shot at, like "C5: hit, turn 7".
+func (g *Game) cellInfo(side Side, xy XY) string {
+	c := g.Boards[side].view(SideSelf).Cells[xy.Y][xy.X]
+	if t := g.shotTurns(side)[xy.Y][xy.X]; t > 0 {
+		return g.tr("%s: %s, turn %d", g.Lang.cell(xy), g.tr(cellNames[c]), t)
+	}
+	return fmt.Sprintf("%s: %s", g.Lang.cell(xy), g.tr(cellNames[c]))
+}
+
+// drawTooltip tells when the cell under the mouse was shot at, next to the mouse.
+func (g *Game) drawTooltip(screen *ebiten.Image) {
+	if g.Menu != nil {
+		return
+	}
+	cx, cy := ebiten.CursorPosition()
+	for side := SideSelf; side <= SidePeer; side++ {
+		if !g.Grid.inBoard(cx, cy, side) {
+			continue
+		}
+		xy := g.Grid.pos2Cell(cx, cy, side)
+		if g.shotTurns(side)[xy.Y][xy.X] == 0 {
+			return
+		}
+		s := g.cellInfo(side, xy)
+		face := g.Theme.face(cellSize * 0.4)
+		w, h := text.Measure(s, face, 0)
+		const pad = 4
+		sw, sh := screen.Bounds().Dx(), screen.Bounds().Dy()
+		// The tooltip stays on the screen, left or above the mouse near the edges.
+		x := min(float32(cx)+cellSize/2, float32(sw)-float32(w)-2*pad)
+		y := min(float32(cy)+cellSize/2, float32(sh)-float32(h)-2*pad)
+		bg := color.RGBA(g.Theme.Background)
+		bg.A = 0xdd
+		vector.DrawFilledRect(screen, x, y, float32(w)+2*pad, float32(h)+2*pad, bg, false)
+		vector.StrokeRect(screen, x, y, float32(w)+2*pad, float32(h)+2*pad, 1, color.RGBA(g.Theme.Text), false)
+		opts := &text.DrawOptions{}
+		opts.GeoM.Translate(float64(x)+pad, float64(y)+pad)
+		opts.ColorScale.ScaleWithColor(color.RGBA(g.Theme.Text))
+		text.Draw(screen, s, face, opts)
+		return
+	}
+}
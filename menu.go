@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const menuLineSize = cellSize * 7 / 16
+
+// Menu is the settings screen shown over the boards.
+// The options go first, then the key bindings of the controls.
+type Menu struct {
+	Row     int
+	Waiting bool // for the key to bind to the control of the row.
+	keys    []ebiten.Key
+}
+
+// MenuOption is the setting changed by left and right in the menu.
+type MenuOption struct {
+	Name   string
+	Value  func(g *Game) string
+	Change func(g *Game, step int)
+}
+
+var menuOptions = []MenuOption{
+	{"Keys", func(g *Game) string { return g.Settings.Preset }, func(g *Game, step int) {
+		i := slices.Index(presetNames, g.Settings.Preset)
+		// The changes are dropped with the preset.
+		g.Settings.Preset = presetNames[(i+step+len(presetNames))%len(presetNames)]
+		g.Settings.Keys = Keymap{}
+		g.Keymap = g.Settings.keymap()
+	}},
+	{"Theme", func(g *Game) string { return g.Theme.Name }, func(g *Game, step int) {
+		i := (themeIndex(g.Theme.Name) + step + len(themeList)) % len(themeList)
+		g.Theme = themeList[i]
+		g.Settings.Theme = g.Theme.Name
+	}},
+	{"Language", func(g *Game) string { return g.Lang.Name }, func(g *Game, step int) {
+		i := slices.Index(langList, g.Lang)
+		g.Lang = langList[(i+step+len(langList))%len(langList)]
+		g.Settings.Lang = g.Lang.Code
+	}},
+	{"Animations", func(g *Game) string { return g.tr(onOff(!g.Settings.NoAnimations)) }, func(g *Game, step int) {
+		g.Settings.NoAnimations = !g.Settings.NoAnimations
+		g.Animate = !g.Settings.NoAnimations
+	}},
+	{"Speech", func(g *Game) string {
+		if g.Announcer == nil || g.Announcer.Speech == nil {
+			return g.tr("not available")
+		}
+		return g.tr(onOff(g.Settings.Speech))
+	}, func(g *Game, step int) {
+		g.Settings.Speech = !g.Settings.Speech
+	}},
+	{"Audio cues", func(g *Game) string { return g.tr(onOff(g.Settings.Cues)) }, func(g *Game, step int) {
+		g.Settings.Cues = !g.Settings.Cues
+	}},
+	volumeOption("Volume", func(s *Settings) *int { return &s.Volume }),
+	volumeOption("Sounds", func(s *Settings) *int { return &s.SoundVolume }),
+	volumeOption("Music", func(s *Settings) *int { return &s.MusicVolume }),
+}
+
+// volumeOption changes the volume of the settings from 0 to maxVolume.
+func volumeOption(name string, volume func(s *Settings) *int) MenuOption {
+	return MenuOption{name, func(g *Game) string {
+		v := *volume(g.Settings)
+		return strings.Repeat("|", v) + strings.Repeat(".", maxVolume-v)
+	}, func(g *Game, step int) {
+		v := volume(g.Settings)
+		*v = min(max(*v+step, 0), maxVolume)
+	}}
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
+
+// menuRows returns the number of the rows in the menu.
+func menuRows() int {
+	return len(menuOptions) + int(numControls-ControlUp)
+}
+
+// control returns the control of the row, if any.
+func (m *Menu) control() (Control, bool) {
+	if m.Row < len(menuOptions) {
+		return ControlNone, false
+	}
+	return ControlUp + Control(m.Row-len(menuOptions)), true
+}
+
+// update handles the keys just pressed while the menu is shown.
+func (m *Menu) update(g *Game) {
+	m.keys = inpututil.AppendJustPressedKeys(m.keys[:0])
+	for _, k := range m.keys {
+		if !m.Waiting {
+			m.handle(g, g.Keymap.control(k))
+			continue
+		}
+		m.Waiting = false
+		if k == ebiten.KeyEscape {
+			continue
+		}
+		c, _ := m.control()
+		g.Settings.bind(c, k)
+		g.Keymap = g.Settings.keymap()
+		g.Message = g.tr("%s is bound to %s", g.tr(c.String()), k)
+	}
+}
+
+// handle handles the control of the player in the menu.
+func (m *Menu) handle(g *Game, c Control) {
+	if m.Waiting {
+		m.Waiting = c != ControlCancel
+		return
+	}
+	_, binding := m.control()
+	switch c {
+	case ControlUp:
+		m.Row = (m.Row - 1 + menuRows()) % menuRows()
+	case ControlDown:
+		m.Row = (m.Row + 1) % menuRows()
+	case ControlLeft, ControlRight, ControlFire:
+		if !binding {
+			step := 1
+			if c == ControlLeft {
+				step = -1
+			}
+			menuOptions[m.Row].Change(g, step)
+		} else if c == ControlFire {
+			m.Waiting = true
+		}
+	case ControlCancel, ControlMenu:
+		g.Menu = nil
+		g.Message = g.tr("Settings saved")
+		if err := g.Settings.save(); err != nil {
+			g.Message = err.Error()
+		}
+	}
+}
+
+// lines returns the text of the rows of the menu.
+func (m *Menu) lines(g *Game) []string {
+	var lines []string
+	for _, o := range menuOptions {
+		lines = append(lines, fmt.Sprintf("%s: < %s >", g.tr(o.Name), o.Value(g)))
+	}
+	for c := ControlUp; c < numControls; c++ {
+		keys := g.Keymap.keyNames(c)
+		if m.Waiting && len(lines) == m.Row {
+			keys = g.tr("press a key, Escape to cancel")
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", g.tr(c.String()), keys))
+	}
+	return lines
+}
+
+// draw draws the menu over the boards, scrolled to the row chosen.
+func (m *Menu) draw(g *Game, screen *ebiten.Image) {
+	w, h := g.Grid.pixelSize(g.Rules.Weapons)
+	left, top := float32(g.Grid.Left), float32(g.Grid.Top)
+	vector.DrawFilledRect(screen, left, top+cellSize, float32(w), float32(h-cellSize), color.RGBA{0, 0, 0, 0xdd}, false)
+	face := g.Theme.face(cellSize * 0.4)
+	lines := m.lines(g)
+	for i := range lines {
+		if i == m.Row {
+			lines[i] = "> " + lines[i]
+		}
+	}
+	// The message row is left for the result of binding, and the last row for the help.
+	shown := (h-cellSize*2)/menuLineSize - 1
+	first := min(max(m.Row-shown/2, 0), max(len(lines)-shown, 0))
+	lines = append(lines[first:min(first+shown, len(lines))], g.tr("Up/Down to choose, Left/Right to change, Fire to bind, Menu to save"))
+	for i, line := range lines {
+		opts := &text.DrawOptions{}
+		opts.GeoM.Translate(float64(left)+cellSize, float64(top)+cellSize*1.25+float64(i*menuLineSize))
+		text.Draw(screen, line, face, opts)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+)
+
+// freeCells returns the cells which are not occupied by anything.
+func (b *Board) freeCells() []XY {
+	var cells []XY
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if b.Cells[y][x] == CellEmpty {
+				cells = append(cells, XY{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// placeIslands puts n islands at random free cells.
+// Islands are neutral, so they are visible to both players.
+func (b *Board) placeIslands(n int) {
+	cells := b.freeCells()
+	b.Game.Rand.Shuffle(len(cells), func(i, j int) {
+		cells[i], cells[j] = cells[j], cells[i]
+	})
+	for i := 0; i < n && i < len(cells); i++ {
+		b.Cells[cells[i].Y][cells[i].X] = CellRock
+	}
+}
+
+// placeMines puts n mines at random free cells.
+func (b *Board) placeMines(n int) {
+	cells := b.freeCells()
+	b.Game.Rand.Shuffle(len(cells), func(i, j int) {
+		cells[i], cells[j] = cells[j], cells[i]
+	})
+	for i := 0; i < n && i < len(cells); i++ {
+		b.Cells[cells[i].Y][cells[i].X] = CellMine
+	}
+}
+
+// mines returns the number of the mines hidden on the board, not exploded yet.
+func (b *Board) mines() int {
+	n := 0
+	for _, row := range b.Cells {
+		for _, c := range row {
+			if c == CellMine {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// putMine hides the mine at the sea cell, as long as the rules allow more mines.
+func (b *Board) putMine(xy XY) error {
+	if !inBoard(xy) {
+		return fmt.Errorf("bad mine %s", xy)
+	}
+	if n := b.Game.Rules.Mines; b.mines() >= n {
+		return fmt.Errorf("no more mines, all %d are hidden", n)
+	}
+	if c := b.Cells[xy.Y][xy.X]; c != CellEmpty && c != CellOily {
+		return fmt.Errorf("mine %s is not in the sea", xy)
+	}
+	b.Cells[xy.Y][xy.X] = CellMine
+	return nil
+}
+
+// hideMine hides the mine of the player at the cell, or takes the mine there back.
+func (g *Game) hideMine(xy XY) {
+	b := g.Boards[SideSelf]
+	g.CursorOwn = xy
+	if b.Cells[xy.Y][xy.X] == CellMine {
+		b.Cells[xy.Y][xy.X] = CellEmpty
+	} else if err := b.putMine(xy); err != nil {
+		g.Message = g.tr("Mines can only be hidden in the sea")
+		return
+	}
+	g.minesHidden()
+}
+
+// minesHidden tells how many mines the player has to hide yet,
+// and starts the turns when all of them are hidden.
+func (g *Game) minesHidden() {
+	if left := g.Rules.Mines - g.Boards[SideSelf].mines(); left > 0 {
+		g.Message = g.trn(left, "Hide %d more mine on your board", "Hide %d more mines on your board", left)
+		return
+	}
+	g.PlacingMines = false
+	g.Message = g.tr("Select the cell to hit")
+}
+
+// handleMineControl handles the control when the player is hiding the mines.
+// Cancel hides the rest of them at random.
+func (g *Game) handleMineControl(c Control) error {
+	d, ok := directions[c]
+	switch {
+	case c == ControlCancel:
+		b := g.Boards[SideSelf]
+		b.placeMines(g.Rules.Mines - b.mines())
+		g.PlacingMines = false
+		g.Message = g.tr("Select the cell to hit")
+	case c == ControlFire:
+		g.hideMine(g.CursorOwn)
+	case ok:
+		g.CursorOwn.X = (g.CursorOwn.X + d.X + Ncells) % Ncells
+		g.CursorOwn.Y = (g.CursorOwn.Y + d.Y + Ncells) % Ncells
+	}
+	return nil
+}
+
+// mineBlast punishes the side which has hit a mine.
+// Depending on the rules, either the side loses the next turn,
+// or one of its own ships is damaged.
+func (g *Game) mineBlast(side Side) {
+	if !g.Rules.MineDamage {
+		g.Skips[side] = true
+		g.Message = g.tr("You have hit a mine and lose the next turn")
+		if side == SidePeer {
+			g.Message = g.tr("The peer has hit a mine and loses the next turn")
+		}
+		return
+	}
+	b := g.Boards[side]
+	var cells []XY
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if b.Cells[y][x] == CellShip {
+				cells = append(cells, XY{x, y})
+			}
+		}
+	}
+	g.Message = g.tr("You have hit a mine")
+	if side == SidePeer {
+		g.Message = g.tr("The peer has hit a mine")
+	}
+	if len(cells) == 0 {
+		return
+	}
+	// The mine, not the opponent, has sunk the ship.
+	if s := b.damage(cells[g.Rand.Intn(len(cells))]); s != nil {
+		left := b.shipsLeft()
+		if side == SideSelf {
+			g.Message = g.trn(left, "You have hit a mine and lost a %d-ship, %d ship left", "You have hit a mine and lost a %d-ship, %d ships left", len(s.Cells), left)
+		} else {
+			g.Message = g.trn(left, "The peer has hit a mine and lost a %d-ship, %d ship left", "The peer has hit a mine and lost a %d-ship, %d ships left", len(s.Cells), left)
+		}
+	}
+}
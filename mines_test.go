@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var minedBoard = []string{
+	"+.......",
+	"........",
+	"..##....",
+	"........",
+	"........",
+	"........",
+	"........",
+	".......+",
+}
+
+func TestMineSkipsTurn(t *testing.T) {
+	g := testGame(t, Rules{Mines: 2}, minedBoard, minedBoard)
+	if again, err := g.shoot(SideSelf, WeaponShot, XY{0, 0}); err != nil || again {
+		t.Fatalf("the shot at the mine: again %v, %v", again, err)
+	}
+	if !g.Skips[SideSelf] || g.Boards[SidePeer].Cells[0][0] != CellBlast {
+		t.Fatalf("the mine has not exploded: skips %v, cell %v", g.Skips[SideSelf], g.Boards[SidePeer].Cells[0][0])
+	}
+	if next := g.nextTurn(SideSelf); next != SidePeer {
+		t.Fatalf("the turn after the mine goes to %d", next)
+	}
+	// The side hitting the mine skips its next turn, once.
+	if next := g.nextTurn(SidePeer); next != SidePeer || g.Skips[SideSelf] {
+		t.Errorf("the turn after the peer goes to %d, skips %v", next, g.Skips[SideSelf])
+	}
+	if next := g.nextTurn(SidePeer); next != SideSelf {
+		t.Errorf("the turn after the skipped one goes to %d", next)
+	}
+}
+
+func TestMineSkipAfterPeer(t *testing.T) {
+	g := testGame(t, Rules{Mines: 2}, minedBoard, minedBoard)
+	if _, err := g.shoot(SidePeer, WeaponShot, XY{7, 7}); err != nil {
+		t.Fatal(err)
+	}
+	if next := g.nextTurn(SidePeer); next != SideSelf {
+		t.Fatalf("the turn after the peer goes to %d", next)
+	}
+	if next := g.nextTurn(SideSelf); next != SideSelf {
+		t.Errorf("the player plays %d after the mine of the peer", next)
+	}
+	if g.Message != "The peer has hit a mine and loses the next turn" {
+		t.Errorf("got message %q", g.Message)
+	}
+}
+
+func TestMineDamage(t *testing.T) {
+	g := testGame(t, Rules{Mines: 2, MineDamage: true}, minedBoard, minedBoard)
+	if again, err := g.shoot(SideSelf, WeaponShot, XY{7, 7}); err != nil || again {
+		t.Fatalf("the shot at the mine: again %v, %v", again, err)
+	}
+	if g.Skips[SideSelf] {
+		t.Error("the turn is skipped with the mine damage")
+	}
+	own := g.Boards[SideSelf]
+	if own.Lives != 1 || own.Cells[2][2] != CellFire && own.Cells[2][3] != CellFire {
+		t.Errorf("the own ship is not damaged: lives %d, %q", own.Lives, rows(own.Cells)[2])
+	}
+	if g.Boards[SidePeer].Lives != 2 {
+		t.Error("the mine has damaged the peer")
+	}
+}
+
+func TestPlaceMines(t *testing.T) {
+	g := testGame(t, Rules{}, emptyBoard, []string{
+		"^^^^^^^^",
+		"####^###",
+		"^^^^^^^^",
+		"^^^^^^^^",
+		"........",
+		"^^^^^^^^",
+		"^^^^^^^^",
+		"^^^^^^^^",
+	})
+	b := g.Boards[SidePeer]
+	b.placeMines(5)
+	if got := rows(b.Cells); strings.Count(got[4], "+") != 5 {
+		t.Errorf("got %q, want 5 mines in the only free row", got[4])
+	}
+	b.placeMines(5)
+	if got := rows(b.Cells); got[4] != "++++++++" || got[1] != "####^###" {
+		t.Errorf("got %q, want the free cells mined and the ships left", got)
+	}
+}
+
+func TestMineDamageSinks(t *testing.T) {
+	g := testGame(t, Rules{Mines: 2, MineDamage: true}, []string{
+		"........",
+		"........",
+		"..#.....",
+		"........",
+		"........",
+		".....#..",
+		"........",
+		"........",
+	}, minedBoard)
+	if _, err := g.shoot(SideSelf, WeaponShot, XY{7, 7}); err != nil {
+		t.Fatal(err)
+	}
+	// The mine, not the peer, has sunk the ship.
+	if want := "You have hit a mine and lost a 1-ship, 1 ship left"; g.Message != want {
+		t.Errorf("got message %q, want %q", g.Message, want)
+	}
+	turn := g.History[len(g.History)-1]
+	if len(turn.Results) != 2 || turn.Results[0] != "H8 mine" || !strings.HasPrefix(turn.Results[1], "blast ") || !strings.HasSuffix(turn.Results[1], " sunk") {
+		t.Errorf("got the results %q, want the mine and the blast", turn.Results)
+	}
+	if len(turn.Cells) != 1 || turn.Cells[0] != (XY{7, 7}) {
+		t.Errorf("got the cells %v of the peer board", turn.Cells)
+	}
+	if event := g.Events[len(g.Events)-1]; !strings.HasSuffix(event, ", the mine has sunk your 1-ship") {
+		t.Errorf("got the event %q", event)
+	}
+	if shots, hits := g.shotStats(SideSelf); shots != 1 || hits != 0 {
+		t.Errorf("got %d shots and %d hits, want the blast not to count", shots, hits)
+	}
+}
+
+func TestPutMine(t *testing.T) {
+	// The board has one mine already.
+	g := testGame(t, Rules{Mines: 3}, viewBoard, emptyBoard)
+	b := g.Boards[SideSelf]
+	for _, tc := range []struct {
+		at XY
+		ok bool
+	}{
+		{XY{0, 0}, false}, // the ship.
+		{XY{3, 2}, false}, // the island.
+		{XY{8, 0}, false},
+		{XY{2, 0}, true},
+		{XY{2, 0}, false}, // the mine.
+		{XY{7, 7}, true},
+		{XY{6, 7}, false}, // no more mines.
+	} {
+		if err := b.putMine(tc.at); (err == nil) != tc.ok {
+			t.Errorf("putMine(%s): %v, want ok %v", tc.at, err, tc.ok)
+		}
+	}
+	if n := b.mines(); n != 3 {
+		t.Errorf("%d mines are hidden, want 3", n)
+	}
+}
+
+func TestSetupHidesRestOfMines(t *testing.T) {
+	g := NewGame(Rules{Mines: 3}, nil)
+	place := func(y Shipyard) error {
+		if err := y.PutMine(XY{0, 0}); err != nil {
+			return err
+		}
+		return y.AddRandomShips()
+	}
+	for side, b := range g.Boards {
+		if err := b.setup(30, place); err != nil {
+			t.Fatal(err)
+		}
+		if b.Cells[0][0] != CellMine || b.mines() != 3 {
+			t.Errorf("board %d: got %q, want the mine at A1 and 2 more", side, rows(b.Cells))
+		}
+	}
+	// The player hides the rest later.
+	g.PlacingMines = true
+	if err := g.Boards[SideSelf].setup(30, place); err != nil {
+		t.Fatal(err)
+	}
+	if n := g.Boards[SideSelf].mines(); n != 1 {
+		t.Errorf("%d mines are hidden for the player, want 1", n)
+	}
+}
+
+func TestHideMines(t *testing.T) {
+	g := testGame(t, Rules{Mines: 3}, []string{
+		"##......",
+		"........",
+		"...^....",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	}, emptyBoard)
+	g.PlacingMines = true
+	b := g.Boards[SideSelf]
+	for _, c := range []Control{ControlFire, ControlRight, ControlRight, ControlFire, ControlRight, ControlFire, ControlFire} {
+		if err := g.handleControl(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first one is at the ship, the last one takes the mine back.
+	if got := rows(b.Cells)[0]; got != "##+....." {
+		t.Errorf("got %q after hiding", got)
+	}
+	if want := "Hide 2 more mines on your board"; g.Message != want {
+		t.Errorf("got message %q, want %q", g.Message, want)
+	}
+	if err := g.handleControl(ControlDown); err != nil || g.CursorOwn != (XY{3, 1}) {
+		t.Fatalf("the cursor is at %s, %v", g.CursorOwn, err)
+	}
+	if err := g.handleControl(ControlFire); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.handleControl(ControlCancel); err != nil {
+		t.Fatal(err)
+	}
+	if g.PlacingMines || b.mines() != 3 || b.Cells[1][3] != CellMine {
+		t.Errorf("after the random rest: placing %v, %q", g.PlacingMines, rows(b.Cells))
+	}
+	if g.Message != "Select the cell to hit" {
+		t.Errorf("got message %q", g.Message)
+	}
+}
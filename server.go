@@ -0,0 +1,443 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server serves the games over HTTP, so that they can be played by other programs.
+// The human player is the one calling the API, and the peer replies at once.
+type Server struct {
+	Bot     string        // the bot command for the games with the "bot" opponent.
+	Timeout time.Duration // for the bot replies.
+	Delay   time.Duration // of the broadcast to the spectators.
+
+	mu     sync.Mutex
+	games  map[string]*serverGame
+	lastID int
+}
+
+type serverGame struct {
+	mu        sync.Mutex
+	game      *Game
+	opponent  string
+	snapshots []snapshot
+	chat      []ChatLine
+	lastLine  int
+}
+
+type apiRules struct {
+	Weapons    bool `json:"weapons"`
+	Mines      int  `json:"mines"`
+	MineDamage bool `json:"mine_damage"`
+	Islands    int  `json:"islands"`
+	Moving     bool `json:"moving"`
+}
+
+type apiNewGame struct {
+	Rules    apiRules `json:"rules"`
+	Opponent string   `json:"opponent"`
+}
+
+type apiWeapon struct {
+	Name     string `json:"name"`
+	Charges  int    `json:"charges"` // -1 means unlimited.
+	Cooldown int    `json:"cooldown"`
+	Ready    bool   `json:"ready"`
+}
+
+type apiGame struct {
+	ID       string      `json:"id"`
+	Rules    apiRules    `json:"rules"`
+	Opponent string      `json:"opponent"`
+	Turn     string      `json:"turn"`
+	Over     bool        `json:"over"`
+	Message  string      `json:"message"`
+	Own      []string    `json:"own"`
+	Peer     []string    `json:"peer"`
+	Weapons  []apiWeapon `json:"weapons,omitempty"`
+}
+
+type apiTurn struct {
+	Player  string   `json:"player"`
+	Action  string   `json:"action"`
+	Results []string `json:"results"`
+}
+
+type apiFire struct {
+	Cell   string `json:"cell"`
+	Weapon string `json:"weapon"`
+}
+
+type apiMove struct {
+	Cell      string `json:"cell"`
+	Direction string `json:"direction"`
+}
+
+type apiTurns struct {
+	Turns []apiTurn `json:"turns"`
+	Game  *apiGame  `json:"game"`
+}
+
+// apiError is the error with the HTTP status.
+type apiError struct {
+	Status int
+	Err    string
+}
+
+func (e *apiError) Error() string {
+	return e.Err
+}
+
+func badRequest(format string, args ...any) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...any) error {
+	return &apiError{http.StatusConflict, fmt.Sprintf(format, args...)}
+}
+
+// Handler returns the handler of the API, see README.md.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /games", s.handle(s.create))
+	mux.HandleFunc("GET /games", s.handle(s.list))
+	mux.HandleFunc("GET /games/{id}", s.handle(s.get))
+	mux.HandleFunc("POST /games/{id}/fire", s.handle(s.fire))
+	mux.HandleFunc("POST /games/{id}/move", s.handle(s.move))
+	mux.HandleFunc("GET /games/{id}/history", s.handle(s.history))
+	mux.HandleFunc("DELETE /games/{id}", s.handle(s.delete))
+	mux.HandleFunc("GET /games/{id}/watch", s.handle(s.watch))
+	mux.HandleFunc("GET /games/{id}/chat", s.handle(s.getChat))
+	mux.HandleFunc("POST /games/{id}/chat", s.handle(s.postChat))
+	return mux
+}
+
+// handle wraps the API call: encodes its result or its error as JSON.
+func (s *Server) handle(f func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := f(r)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := err.(*apiError); ok {
+				status = e.Status
+			}
+			w.WriteHeader(status)
+			res = map[string]string{"error": err.Error()}
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Printf("%s %s: %v", r.Method, r.URL, err)
+		}
+	}
+}
+
+// lookup returns the game by the id in the path, locked after the server is unlocked.
+func (s *Server) lookup(r *http.Request) (*serverGame, error) {
+	s.mu.Lock()
+	sg, ok := s.games[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &apiError{http.StatusNotFound, fmt.Sprintf("no game %q", r.PathValue("id"))}
+	}
+	sg.mu.Lock()
+	return sg, nil
+}
+
+func (s *Server) create(r *http.Request) (any, error) {
+	var req apiNewGame
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("bad game: %v", err)
+	}
+	rules := Rules{
+		Weapons:    req.Rules.Weapons,
+		Mines:      req.Rules.Mines,
+		MineDamage: req.Rules.MineDamage,
+		Islands:    req.Rules.Islands,
+		Moving:     req.Rules.Moving,
+	}
+	if rules.Mines < 0 || rules.Islands < 0 || rules.Mines+rules.Islands > Ncells*Ncells/4 {
+		return nil, badRequest("too many mines or islands")
+	}
+	if req.Opponent == "" {
+		req.Opponent = "hunt"
+	}
+	var peer Strategy
+	switch search, ok := builtinStrategies[req.Opponent]; {
+	case ok:
+		ai := NewAIStrategy(rules)
+		ai.Search = search
+		peer = ai
+	case req.Opponent == "bot" && s.Bot != "":
+		bot, err := NewBotStrategy(s.Bot, rules, s.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		peer = bot
+	default:
+		return nil, badRequest("unknown opponent %q", req.Opponent)
+	}
+	g := NewGame(rules, peer)
+	if err := g.init(); err != nil {
+		g.finish()
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.games == nil {
+		s.games = make(map[string]*serverGame)
+	}
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+	sg := &serverGame{game: g, opponent: req.Opponent}
+	sg.snap(s.Delay)
+	s.games[id] = sg
+	return sg.state(id), nil
+}
+
+// list returns the games to watch.
+// The server and a game are never locked together, so the games are collected first.
+func (s *Server) list(r *http.Request) (any, error) {
+	var ids []string
+	var found []*serverGame
+	s.mu.Lock()
+	for id := 1; id <= s.lastID; id++ {
+		if sg, ok := s.games[strconv.Itoa(id)]; ok {
+			ids = append(ids, strconv.Itoa(id))
+			found = append(found, sg)
+		}
+	}
+	s.mu.Unlock()
+	games := []*apiGame{}
+	for i, sg := range found {
+		sg.mu.Lock()
+		st := sg.state(ids[i])
+		sg.mu.Unlock()
+		// Anybody may list the games, so the boards are not shown.
+		st.Own, st.Peer = nil, nil
+		games = append(games, st)
+	}
+	return games, nil
+}
+
+func (s *Server) get(r *http.Request) (any, error) {
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	return sg.state(r.PathValue("id")), nil
+}
+
+func (s *Server) fire(r *http.Request) (any, error) {
+	var req apiFire
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("bad shot: %v", err)
+	}
+	xy, err := parseXY(req.Cell)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	w := WeaponShot
+	if req.Weapon != "" {
+		var ok bool
+		if w, ok = parseWeapon(req.Weapon); !ok {
+			return nil, badRequest("unknown weapon %q", req.Weapon)
+		}
+	}
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	g := sg.game
+	if err := sg.playable(); err != nil {
+		return nil, err
+	}
+	if w != WeaponShot && !g.Rules.Weapons {
+		return nil, badRequest("weapons are not allowed in this game")
+	}
+	if !g.Arsenals[SideSelf].ready(w) {
+		return nil, conflict("%s", g.Arsenals[SideSelf].status(g.Lang, w))
+	}
+	n := len(g.History)
+	g.Arsenals[SideSelf].Selected = w
+	g.CursorSelf = xy
+	if err := g.selfShoot(); err != nil {
+		g.Error = err
+	}
+	return sg.reply(r.PathValue("id"), n, s.Delay), nil
+}
+
+func (s *Server) move(r *http.Request) (any, error) {
+	var req apiMove
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("bad move: %v", err)
+	}
+	xy, err := parseXY(req.Cell)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	d, ok := cliDirections[strings.ToLower(req.Direction)]
+	if !ok {
+		return nil, badRequest("bad direction %q, want up, down, left or right", req.Direction)
+	}
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	g := sg.game
+	if err := sg.playable(); err != nil {
+		return nil, err
+	}
+	if !g.Rules.Moving {
+		return nil, badRequest("moving ships is not allowed in this game")
+	}
+	n := len(g.History)
+	g.selectShip(xy)
+	if g.MovingShip == nil {
+		return nil, conflict("%s", g.Message)
+	}
+	if err := g.selfMoveShip(d); err != nil {
+		g.Error = err
+	}
+	if len(g.History) == n {
+		g.MovingShip = nil
+		return nil, conflict("%s", g.Message)
+	}
+	return sg.reply(r.PathValue("id"), n, s.Delay), nil
+}
+
+func (s *Server) history(r *http.Request) (any, error) {
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	return sg.turns(0), nil
+}
+
+func (s *Server) delete(r *http.Request) (any, error) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	sg, ok := s.games[id]
+	delete(s.games, id)
+	s.mu.Unlock()
+	if !ok {
+		return nil, &apiError{http.StatusNotFound, fmt.Sprintf("no game %q", id)}
+	}
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if sg.game.Error == nil {
+		sg.game.Error = fmt.Errorf("Stopped by player")
+		sg.game.finish()
+		sg.snap(s.Delay)
+	}
+	return sg.state(id), nil
+}
+
+// playable returns the error if the player cannot act now.
+func (sg *serverGame) playable() error {
+	if sg.game.Error != nil {
+		return conflict("the game is over: %v", sg.game.Error)
+	}
+	if sg.game.WhoseTurn != SideSelf {
+		return conflict("not your turn")
+	}
+	return nil
+}
+
+// reply lets the peer reply to the player action,
+// and returns the turns done since the history had n turns.
+func (sg *serverGame) reply(id string, n int, delay time.Duration) *apiTurns {
+	g := sg.game
+	for g.Error == nil && g.WhoseTurn == SidePeer {
+		g.peerStep()
+	}
+	if g.Error != nil {
+		g.finish()
+	}
+	sg.snap(delay)
+	return &apiTurns{Turns: sg.turns(n), Game: sg.state(id)}
+}
+
+// turns returns the history from the turn n as the player sees it.
+func (sg *serverGame) turns(n int) []apiTurn {
+	turns := []apiTurn{}
+	for _, t := range sg.game.History[n:] {
+		at := apiTurn{Player: "you", Action: t.Action.String(), Results: t.Results}
+		if t.Side == SidePeer {
+			at.Player = "peer"
+			if t.Action.Move {
+				// The player does not know which ship has moved.
+				at.Action = Action{Move: true}.String()
+			}
+		}
+		if at.Results == nil {
+			at.Results = []string{}
+		}
+		turns = append(turns, at)
+	}
+	return turns
+}
+
+// state returns what the player knows about the game.
+func (sg *serverGame) state(id string) *apiGame {
+	g := sg.game
+	r := g.Rules
+	st := &apiGame{
+		ID:       id,
+		Rules:    apiRules{r.Weapons, r.Mines, r.MineDamage, r.Islands, r.Moving},
+		Opponent: sg.opponent,
+		Turn:     "you",
+		Over:     g.Error != nil,
+		Message:  g.Message,
+		Own:      viewRows(g.Boards[SideSelf].view(SideSelf)),
+		Peer:     viewRows(g.Boards[SidePeer].view(SideSelf)),
+	}
+	if g.WhoseTurn == SidePeer {
+		st.Turn = "peer"
+	}
+	if g.Error != nil {
+		st.Message = g.Error.Error()
+	}
+	if r.Weapons {
+		a := g.Arsenals[SideSelf]
+		for w := Weapon(0); w < numWeapons; w++ {
+			st.Weapons = append(st.Weapons, apiWeapon{
+				Name:     strings.ToLower(w.String()),
+				Charges:  a.Charges[w],
+				Cooldown: a.Cooldown[w],
+				Ready:    a.ready(w),
+			})
+		}
+	}
+	return st
+}
+
+// viewRows returns the rows of the view drawn with the glyphs of the command line.
+func viewRows(v *View) []string {
+	rows := make([]string, Ncells)
+	for y, row := range v.Cells {
+		b := make([]byte, len(row))
+		for x, c := range row {
+			b[x] = cellGlyphs[c]
+		}
+		rows[y] = string(b)
+	}
+	return rows
+}
+
+// runServer serves the API until it fails.
+func runServer(addr, bot string, timeout, delay time.Duration) error {
+	s := &Server{Bot: bot, Timeout: timeout, Delay: delay}
+	log.Printf("serving the games at http://%s/games", addr)
+	return http.ListenAndServe(addr, s.Handler())
+}
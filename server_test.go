@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// call calls the API of the server, and decodes the reply into res if it is not nil.
+func call(t *testing.T, h http.Handler, method, path, body string, res any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if res != nil && w.Code < 300 {
+		if err := json.NewDecoder(w.Body).Decode(res); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return w.Code
+}
+
+// newGame creates the game on the server and returns its id.
+func newGame(t *testing.T, h http.Handler, body string) string {
+	t.Helper()
+	var st apiGame
+	if code := call(t, h, "POST", "/games", body, &st); code != http.StatusOK {
+		t.Fatalf("POST /games %s: status %d", body, code)
+	}
+	return st.ID
+}
+
+func TestServerListWhileDeleting(t *testing.T) {
+	h := (&Server{}).Handler()
+	const n = 100
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = newGame(t, h, `{}`)
+	}
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if code := call(t, h, "DELETE", "/games/"+id, "", nil); code != http.StatusOK {
+				t.Errorf("DELETE %s: status %d", id, code)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			var games []apiGame
+			if code := call(t, h, "GET", "/games", "", &games); code != http.StatusOK {
+				t.Errorf("GET /games: status %d", code)
+			}
+		}()
+	}
+	wg.Wait()
+	var games []apiGame
+	call(t, h, "GET", "/games", "", &games)
+	if len(games) != 0 {
+		t.Errorf("%d games are left after deleting all", len(games))
+	}
+	if code := call(t, h, "DELETE", "/games/"+ids[0], "", nil); code != http.StatusNotFound {
+		t.Errorf("deleting twice: status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestServerCreate(t *testing.T) {
+	h := (&Server{}).Handler()
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{}`, http.StatusOK},
+		{`{"rules": {"weapons": true, "mines": 3, "islands": 2, "moving": true}, "opponent": "uniform"}`, http.StatusOK},
+		{`{"rules": {"mines": 10, "islands": 10}}`, http.StatusBadRequest},
+		{`{"rules": {"mines": -1}}`, http.StatusBadRequest},
+		{`{"opponent": "nobody"}`, http.StatusBadRequest},
+		{`{"opponent": "bot"}`, http.StatusBadRequest}, // the server has no bot.
+		{`{"rules": `, http.StatusBadRequest},
+	} {
+		var st apiGame
+		code := call(t, h, "POST", "/games", tc.body, &st)
+		if code != tc.code {
+			t.Errorf("POST /games %s: status %d, want %d", tc.body, code, tc.code)
+		}
+		if code == http.StatusOK && (st.ID == "" || st.Turn != "you" || st.Over || len(st.Own) != Ncells || strings.Contains(strings.Join(st.Peer, ""), "#")) {
+			t.Errorf("POST /games %s: got %+v", tc.body, st)
+		}
+	}
+}
+
+func TestServerNotFound(t *testing.T) {
+	h := (&Server{}).Handler()
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{"GET", "/games/1", ""},
+		{"POST", "/games/1/fire", `{"cell": "A1"}`},
+		{"POST", "/games/1/move", `{"cell": "A1", "direction": "up"}`},
+		{"GET", "/games/1/history", ""},
+		{"DELETE", "/games/1", ""},
+	} {
+		if code := call(t, h, tc.method, tc.path, tc.body, nil); code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestServerFire(t *testing.T) {
+	h := (&Server{}).Handler()
+	id := newGame(t, h, `{}`)
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"cell": "Z9"}`, http.StatusBadRequest},
+		{`{"cell": "A1", "weapon": "laser"}`, http.StatusBadRequest},
+		{`{"cell": "A1", "weapon": "radar"}`, http.StatusBadRequest}, // no weapons in this game.
+		{`{"cell": `, http.StatusBadRequest},
+	} {
+		if code := call(t, h, "POST", "/games/"+id+"/fire", tc.body, nil); code != tc.code {
+			t.Errorf("POST fire %s: status %d, want %d", tc.body, code, tc.code)
+		}
+	}
+	var res apiTurns
+	if code := call(t, h, "POST", "/games/"+id+"/fire", `{"cell": "c5"}`, &res); code != http.StatusOK {
+		t.Fatalf("POST fire: status %d", code)
+	}
+	if len(res.Turns) == 0 || res.Turns[0].Player != "you" || res.Turns[0].Action != "shot C5" {
+		t.Errorf("got the turns %+v", res.Turns)
+	}
+	// The peer replies at once.
+	if res.Game.Turn != "you" && !res.Game.Over {
+		t.Errorf("the turn of the %s after the shot", res.Game.Turn)
+	}
+	if cell := res.Game.Peer[4][2]; cell == '~' {
+		t.Errorf("the cell C5 is unknown after the shot")
+	}
+	var hist []apiTurn
+	if code := call(t, h, "GET", "/games/"+id+"/history", "", &hist); code != http.StatusOK || !reflect.DeepEqual(hist, res.Turns) {
+		t.Errorf("GET history: status %d, %+v, want %+v", code, hist, res.Turns)
+	}
+	var st apiGame
+	if code := call(t, h, "GET", "/games/"+id, "", &st); code != http.StatusOK || !slices.Equal(st.Peer, res.Game.Peer) {
+		t.Errorf("GET the game: status %d, %+v", code, st)
+	}
+	if code := call(t, h, "DELETE", "/games/"+id, "", &st); code != http.StatusOK || !st.Over {
+		t.Errorf("DELETE the game: status %d, %+v", code, st)
+	}
+	if code := call(t, h, "POST", "/games/"+id+"/fire", `{"cell": "d5"}`, nil); code != http.StatusNotFound {
+		t.Errorf("POST fire after deleting: status %d", code)
+	}
+}
+
+func TestServerWeapons(t *testing.T) {
+	h := (&Server{}).Handler()
+	id := newGame(t, h, `{"rules": {"weapons": true}}`)
+	var res apiTurns
+	if code := call(t, h, "POST", "/games/"+id+"/fire", `{"cell": "C5", "weapon": "radar"}`, &res); code != http.StatusOK {
+		t.Fatalf("POST fire the radar: status %d", code)
+	}
+	if res.Turns[0].Action != "radar C5" || len(res.Game.Weapons) != int(numWeapons) {
+		t.Errorf("got %+v, weapons %+v", res.Turns[0], res.Game.Weapons)
+	}
+	if code := call(t, h, "POST", "/games/"+id+"/fire", `{"cell": "C5", "weapon": "radar"}`, nil); code != http.StatusConflict {
+		t.Errorf("POST fire the radar again: status %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestServerMove(t *testing.T) {
+	h := (&Server{}).Handler()
+	id := newGame(t, h, `{}`)
+	if code := call(t, h, "POST", "/games/"+id+"/move", `{"cell": "A1", "direction": "up"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST move without moving: status %d, want %d", code, http.StatusBadRequest)
+	}
+	id = newGame(t, h, `{"rules": {"moving": true}}`)
+	var st apiGame
+	call(t, h, "GET", "/games/"+id, "", &st)
+	water := XY{-1, -1}
+	for y, row := range st.Own {
+		if x := strings.IndexByte(row, '.'); x >= 0 {
+			water = XY{x, y}
+			break
+		}
+	}
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{"cell": "A1", "direction": "north"}`, http.StatusBadRequest},
+		{`{"cell": "A0", "direction": "up"}`, http.StatusBadRequest},
+		{`{"cell": "` + water.String() + `", "direction": "up"}`, http.StatusConflict},
+	} {
+		if code := call(t, h, "POST", "/games/"+id+"/move", tc.body, nil); code != tc.code {
+			t.Errorf("POST move %s: status %d, want %d", tc.body, code, tc.code)
+		}
+	}
+	var hist []apiTurn
+	if call(t, h, "GET", "/games/"+id+"/history", "", &hist); len(hist) != 0 {
+		t.Errorf("got the turns %+v after the failed moves", hist)
+	}
+}
+
+func TestServerWatch(t *testing.T) {
+	h := (&Server{}).Handler()
+	id := newGame(t, h, `{}`)
+	var w apiWatch
+	if code := call(t, h, "GET", "/games/"+id+"/watch", "", &w); code != http.StatusOK || w.View != "neutral" {
+		t.Fatalf("GET watch: status %d, view %q", code, w.View)
+	}
+	for _, b := range w.Boards {
+		if rows := strings.Join(b.Rows, ""); strings.Contains(rows, "#") || len(b.Ships) != 0 {
+			t.Errorf("the neutral view shows the ships: %q, %v", b.Rows, b.Ships)
+		}
+	}
+	for _, tc := range []struct {
+		view string
+		code int
+	}{
+		{"full", http.StatusForbidden}, // the ships are seen without the delay.
+		{"peer", http.StatusForbidden},
+		{"sky", http.StatusBadRequest},
+	} {
+		if code := call(t, h, "GET", "/games/"+id+"/watch?view="+tc.view, "", nil); code != tc.code {
+			t.Errorf("GET watch the %s view: status %d, want %d", tc.view, code, tc.code)
+		}
+	}
+	h = (&Server{Delay: time.Hour}).Handler()
+	id = newGame(t, h, `{}`)
+	if code := call(t, h, "GET", "/games/"+id+"/watch?view=full", "", nil); code != http.StatusConflict {
+		t.Errorf("GET watch before the delay: status %d, want %d", code, http.StatusConflict)
+	}
+}
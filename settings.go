@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Settings are the preferences of the player, kept between the games.
+type Settings struct {
+	Preset string `json:"preset"` // of the key bindings, see keyPresets.
+	Keys   Keymap `json:"keys"`   // the bindings changed from the preset.
+	Theme  string `json:"theme"`
+	Lang   string `json:"lang"` // the code of the language, like "ru".
+
+	NoAnimations bool `json:"no_animations,omitempty"`
+	Volume       int  `json:"volume"`       // from 0 to maxVolume, for all the audio.
+	SoundVolume  int  `json:"sound_volume"` // of the sound effects.
+	MusicVolume  int  `json:"music_volume"`
+	Speech       bool `json:"speech,omitempty"` // tell what happens with the text-to-speech.
+	Cues         bool `json:"cues,omitempty"`   // where the cells and the shots are, by ear.
+
+	path string // where the settings are saved, if anywhere.
+}
+
+func defaultSettings() *Settings {
+	return &Settings{Preset: "arrows", Keys: Keymap{}, Volume: maxVolume, SoundVolume: maxVolume, MusicVolume: maxVolume / 2}
+}
+
+// settingsPath returns the path of the settings file of the user.
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "seabattle2", "settings.json"), nil
+}
+
+// loadSettings reads the settings of the user, or returns the default ones if there are none.
+func loadSettings(path string) (*Settings, error) {
+	s := defaultSettings()
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return s, fmt.Errorf("bad settings %s: %w", path, err)
+	}
+	if _, ok := keyPresets[s.Preset]; !ok {
+		err := fmt.Errorf("bad settings %s: unknown preset %q", path, s.Preset)
+		s.Preset = "arrows"
+		return s, err
+	}
+	if s.Keys == nil {
+		s.Keys = Keymap{}
+	}
+	return s, nil
+}
+
+// save writes the settings to the file they were loaded from.
+func (s *Settings) save() error {
+	if s.path == "" {
+		return fmt.Errorf("settings are not saved")
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(b, '\n'), 0o644)
+}
+
+// bind binds the key to the control only, and keeps the changes from the preset.
+func (s *Settings) bind(c Control, k ebiten.Key) {
+	km := s.keymap()
+	for cc, keys := range km {
+		km[cc] = slices.DeleteFunc(slices.Clone(keys), func(kk ebiten.Key) bool { return kk == k })
+	}
+	km[c] = []ebiten.Key{k}
+	s.Keys = Keymap{}
+	for cc, keys := range km {
+		if !slices.Equal(keys, keyPresets[s.Preset][cc]) {
+			s.Keys[cc] = keys
+		}
+	}
+}
+
+// keymap returns the bindings of the preset with the changes.
+func (s *Settings) keymap() Keymap {
+	km := Keymap{}
+	for c, keys := range keyPresets[s.Preset] {
+		km[c] = keys
+	}
+	for c, keys := range s.Keys {
+		km[c] = keys
+	}
+	return km
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type Orientation int
+
+// Ship is a ship placed on the board.
+type Ship struct {
+	ID          int
+	Orientation Orientation
+	Cells       []XY
+	HitAt       []int64 // the tick when the cell was hit, 0 if not hit yet.
+}
+
+const (
+	Horizontal Orientation = iota
+	Vertical
+)
+
+var directions = map[Control]XY{
+	ControlUp:    {0, -1},
+	ControlDown:  {0, 1},
+	ControlLeft:  {-1, 0},
+	ControlRight: {1, 0},
+}
+
+// NewShip creates the straight ship from p0 to p1.
+func NewShip(id int, p0, p1 XY) *Ship {
+	s := &Ship{
+		ID:    id,
+		Cells: seqXY(shipSeq(p0, p1)),
+	}
+	if p0.X == p1.X && p0.Y != p1.Y {
+		s.Orientation = Vertical
+	}
+	s.HitAt = make([]int64, len(s.Cells))
+	return s
+}
+
+// hit registers the hit of the ship cell at the tick.
+func (s *Ship) hit(xy XY, tick int64) {
+	if i := slices.Index(s.Cells, xy); i >= 0 && s.HitAt[i] == 0 {
+		s.HitAt[i] = max(tick, 1)
+	}
+}
+
+// hits returns the number of cells hit.
+func (s *Ship) hits() int {
+	n := 0
+	for _, t := range s.HitAt {
+		if t != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Ship) sunk() bool {
+	return s.hits() == len(s.Cells)
+}
+
+// clone returns the copy of the ship, which does not change when the ship moves or is hit.
+func (s *Ship) clone() *Ship {
+	c := *s
+	c.Cells = slices.Clone(s.Cells)
+	c.HitAt = slices.Clone(s.HitAt)
+	return &c
+}
+
+// around returns the cells touching the ship by sides.
+func (s *Ship) around() []XY {
+	var cells []XY
+	for _, xy := range s.Cells {
+		for _, nb := range []XY{{xy.X, xy.Y - 1}, {xy.X, xy.Y + 1}, {xy.X - 1, xy.Y}, {xy.X + 1, xy.Y}} {
+			if inBoard(nb) && !slices.Contains(s.Cells, nb) && !slices.Contains(cells, nb) {
+				cells = append(cells, nb)
+			}
+		}
+	}
+	return cells
+}
+
+// drawShips draws the hulls of the ships visible in the view.
+func (b *Board) drawShips(screen *ebiten.Image, v *View) {
+	for _, s := range v.Fleet {
+		x0, y0 := b.Game.Grid.cellOrigin(s.Cells[0].X, s.Cells[0].Y, b.Side)
+		last := s.Cells[len(s.Cells)-1]
+		x1, y1 := b.Game.Grid.cellOrigin(last.X, last.Y, b.Side)
+		const inset = 3
+		vector.StrokeRect(screen, min(x0, x1)+inset, min(y0, y1)+inset,
+			abs(x1-x0)+cellSize-2*inset, abs(y1-y0)+cellSize-2*inset,
+			2, color.RGBA(b.Game.Theme.Hull), true)
+	}
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func isShipCell(c Cell) bool {
+	switch c {
+	case CellShip, CellFire, CellSunk:
+		return true
+	}
+	return false
+}
+
+func inBoard(xy XY) bool {
+	return xy.X >= 0 && xy.X < Ncells && xy.Y >= 0 && xy.Y < Ncells
+}
+
+// shipAt returns the ship occupying the cell, or nil.
+func (b *Board) shipAt(xy XY) *Ship {
+	return shipIn(b.Fleet, xy)
+}
+
+// shipIn returns the ship of the fleet occupying the cell, or nil.
+func shipIn(fleet []*Ship, xy XY) *Ship {
+	for _, s := range fleet {
+		if slices.Contains(s.Cells, xy) {
+			return s
+		}
+	}
+	return nil
+}
+
+// canMoveAt returns true if there is the ship at the cell, and it can be moved by d.
+// It is given to the strategies instead of the board.
+func (b *Board) canMoveAt(at, d XY) bool {
+	s := b.shipAt(at)
+	return s != nil && b.canMove(s, d)
+}
+
+// canMove returns true if the ship can be moved by d.
+// Only undamaged ships can move, and only to the cells the opponent knows nothing about.
+// The ship must not touch other ships at the new place.
+func (b *Board) canMove(s *Ship, d XY) bool {
+	if s.hits() > 0 {
+		return false
+	}
+	for _, xy := range s.Cells {
+		n := XY{xy.X + d.X, xy.Y + d.Y}
+		if !inBoard(n) {
+			return false
+		}
+		if !slices.Contains(s.Cells, n) && b.Cells[n.Y][n.X] != CellEmpty {
+			return false
+		}
+		for _, nb := range []XY{{n.X - 1, n.Y}, {n.X + 1, n.Y}, {n.X, n.Y - 1}, {n.X, n.Y + 1}} {
+			if inBoard(nb) && !slices.Contains(s.Cells, nb) && isShipCell(b.Cells[nb.Y][nb.X]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// moveShip moves the ship by d. The caller must check canMove first.
+// All radar scans on the board become stale.
+func (b *Board) moveShip(s *Ship, d XY) {
+	for _, xy := range s.Cells {
+		b.Cells[xy.Y][xy.X] = CellEmpty
+	}
+	for i, xy := range s.Cells {
+		xy = XY{xy.X + d.X, xy.Y + d.Y}
+		s.Cells[i] = xy
+		b.Cells[xy.Y][xy.X] = CellShip
+	}
+	for i := range b.Scans {
+		b.Scans[i].Stale = true
+	}
+}
+
+// toggleMoving switches the player between firing and moving a ship.
+func (g *Game) toggleMoving() {
+	g.Moving = !g.Moving
+	g.MovingShip = nil
+	if g.Moving {
+		g.Message = g.tr("Select the ship to move")
+	} else {
+		g.Message = g.tr("Select the cell to hit")
+	}
+}
+
+// handleMoveControl handles the control when the player is moving a ship.
+func (g *Game) handleMoveControl(c Control) error {
+	d, ok := directions[c]
+	switch {
+	case c == ControlCancel:
+		g.toggleMoving()
+	case c == ControlFire:
+		g.selectShip(g.CursorOwn)
+	case ok && g.MovingShip != nil:
+		return g.selfMoveShip(d)
+	case ok:
+		g.CursorOwn.X = (g.CursorOwn.X + d.X + Ncells) % Ncells
+		g.CursorOwn.Y = (g.CursorOwn.Y + d.Y + Ncells) % Ncells
+	}
+	return nil
+}
+
+// handleMoveClick handles the click at the cell of own board when the player is moving a ship.
+// Clicking at the ship selects it, clicking next to the selected ship moves it there.
+func (g *Game) handleMoveClick(xy XY) error {
+	g.CursorOwn = xy
+	s := g.MovingShip
+	if s == nil || slices.Contains(s.Cells, xy) {
+		g.selectShip(xy)
+		return nil
+	}
+	for _, d := range directions {
+		if slices.Contains(s.Cells, XY{xy.X - d.X, xy.Y - d.Y}) {
+			return g.selfMoveShip(d)
+		}
+	}
+	g.selectShip(xy)
+	return nil
+}
+
+func (g *Game) selectShip(xy XY) {
+	s := g.Boards[SideSelf].shipAt(xy)
+	if s == nil || s.hits() > 0 {
+		g.MovingShip = nil
+		g.Message = g.tr("Select an undamaged ship")
+		return
+	}
+	g.MovingShip = s
+	g.Message = g.tr("Move the ship by one cell")
+}
+
+// selfMoveShip moves the selected ship of the player, which ends the turn.
+func (g *Game) selfMoveShip(d XY) error {
+	b := g.Boards[SideSelf]
+	if !b.canMove(g.MovingShip, d) {
+		g.Message = g.tr("The ship cannot move there")
+		return nil
+	}
+	g.record(SideSelf, Action{Move: true, Target: g.MovingShip.Cells[0], Dir: d}, nil, nil)
+	b.moveShip(g.MovingShip, d)
+	g.CursorOwn = XY{g.CursorOwn.X + d.X, g.CursorOwn.Y + d.Y}
+	g.Moving = false
+	g.MovingShip = nil
+	g.Message = g.tr("You have moved the ship")
+	// The peer only knows that a ship has moved.
+	g.observePeer(false, Action{Move: true})
+	return g.endSelfTurn()
+}
+
+// strategyMove moves the ship of the side as chosen by its strategy.
+func (g *Game) strategyMove(side Side, act Action) error {
+	if !g.Rules.Moving {
+		return fmt.Errorf("moving ships is not allowed")
+	}
+	b := g.Boards[side]
+	s := b.shipAt(act.Target)
+	if s == nil || !b.canMove(s, act.Dir) {
+		return fmt.Errorf("cannot move the ship at %s by %v", act.Target, act.Dir)
+	}
+	g.record(side, act, nil, nil)
+	b.moveShip(s, act.Dir)
+	return nil
+}
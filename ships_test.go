@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanMove(t *testing.T) {
+	g := testGame(t, Rules{Moving: true}, []string{
+		"###.....",
+		"........",
+		"..##^...",
+		"........",
+		"#...#...",
+		"........",
+		"....+...",
+		"....#...",
+	}, emptyBoard)
+	b := g.Boards[SideSelf]
+	b.hitCell(XY{0, 4})
+	up, down, left, right := XY{0, -1}, XY{0, 1}, XY{-1, 0}, XY{1, 0}
+	for _, tc := range []struct {
+		at   XY
+		d    XY
+		want bool
+	}{
+		{XY{0, 0}, right, true},
+		{XY{0, 0}, left, false}, // off the board.
+		{XY{0, 0}, up, false},
+		{XY{0, 0}, down, false},  // touching the ship below by the side.
+		{XY{2, 2}, up, false},    // touching the ship above by the side.
+		{XY{2, 2}, down, true},   // touching the ship by the corner.
+		{XY{2, 2}, right, false}, // the island in the way.
+		{XY{2, 2}, left, true},
+		{XY{0, 4}, up, false}, // sunk.
+		{XY{4, 7}, up, false}, // the mine in the way.
+		{XY{4, 7}, right, true},
+	} {
+		if got := b.canMoveAt(tc.at, tc.d); got != tc.want {
+			t.Errorf("canMoveAt(%s, %v) = %v, want %v", tc.at, tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestCanMoveDamaged(t *testing.T) {
+	g := testGame(t, Rules{Moving: true}, []string{
+		"........",
+		".###....",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	}, emptyBoard)
+	b := g.Boards[SideSelf]
+	if !b.canMoveAt(XY{1, 1}, XY{0, 1}) {
+		t.Fatal("the ship cannot move")
+	}
+	b.hitCell(XY{2, 1})
+	if b.canMoveAt(XY{1, 1}, XY{0, 1}) {
+		t.Error("the damaged ship can move")
+	}
+	if b.canMoveAt(XY{5, 5}, XY{0, 1}) {
+		t.Error("the water can move")
+	}
+}
+
+func TestMoveShip(t *testing.T) {
+	g := testGame(t, Rules{Moving: true, Weapons: true}, []string{
+		"........",
+		".##.....",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	}, emptyBoard)
+	b := g.Boards[SideSelf]
+	b.Scans = []Scan{{Center: XY{1, 1}, Found: true}}
+	s := b.shipAt(XY{1, 1})
+	b.moveShip(s, XY{1, 1})
+	if want := []XY{{2, 2}, {3, 2}}; !slices.Equal(s.Cells, want) {
+		t.Errorf("the ship is at %v, want %v", s.Cells, want)
+	}
+	if got := rows(b.Cells); got[1] != "........" || got[2] != "..##...." {
+		t.Errorf("got %q after the move", got)
+	}
+	if !b.Scans[0].Stale {
+		t.Error("the scan is not stale after the move")
+	}
+	if b.shipAt(XY{1, 1}) != nil || b.shipAt(XY{3, 2}) != s {
+		t.Error("the ship is not found at its new place")
+	}
+}
+
+func TestSelfMoveShip(t *testing.T) {
+	g := testGame(t, Rules{Moving: true}, []string{
+		"##......",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	}, emptyBoard)
+	g.Peer = NewAIStrategy(g.Rules)
+	g.toggleMoving()
+	g.selectShip(XY{1, 0})
+	if err := g.selfMoveShip(XY{-1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.History) != 0 || g.WhoseTurn != SideSelf || g.Message != "The ship cannot move there" {
+		t.Fatalf("the illegal move: %d turns, message %q", len(g.History), g.Message)
+	}
+	if err := g.selfMoveShip(XY{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.History) != 1 || !g.History[0].Action.Move || g.Moving || g.MovingShip != nil {
+		t.Errorf("after the move: history %+v, moving %v", g.History, g.Moving)
+	}
+	if g.WhoseTurn != SidePeer {
+		t.Error("the move has not ended the turn")
+	}
+	if r := <-g.Thinking; r.Err != nil {
+		t.Error(r.Err)
+	}
+}
@@ -0,0 +1,157 @@
+// gen synthesizes the sounds of the game into WAV files.
+// The sounds are made from scratch, so they are free of any license.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+)
+
+const sampleRate = 22050
+
+// noise is the repeatable white noise, so that the files do not change when generated again.
+type noise uint32
+
+func (n *noise) next() float64 {
+	*n = *n*1664525 + 1013904223
+	return float64(*n>>8)/float64(1<<23) - 1
+}
+
+// tone returns the sample of the soft square wave.
+func tone(freq, t float64) float64 {
+	return math.Tanh(3 * math.Sin(2*math.Pi*freq*t))
+}
+
+// envelope rises in the attack and decays exponentially afterwards.
+func envelope(t, attack, decay float64) float64 {
+	if t < attack {
+		return t / attack
+	}
+	return math.Exp(-(t - attack) / decay)
+}
+
+func miss() []float64 {
+	n := noise(1)
+	s := make([]float64, sampleRate*6/10)
+	lp := 0.0
+	for i := range s {
+		t := float64(i) / sampleRate
+		// The splash is the noise getting duller.
+		lp += (n.next() - lp) * (0.5 - 0.4*t/0.6)
+		s[i] = 0.8 * lp * envelope(t, 0.01, 0.15)
+	}
+	return s
+}
+
+func hit() []float64 {
+	n := noise(2)
+	s := make([]float64, sampleRate*7/10)
+	lp := 0.0
+	for i := range s {
+		t := float64(i) / sampleRate
+		lp += (n.next() - lp) * 0.15
+		boom := math.Sin(2 * math.Pi * (90*t - 60*t*t))
+		s[i] = (0.5*boom + 1.2*lp) * envelope(t, 0.005, 0.18)
+	}
+	return s
+}
+
+func sink() []float64 {
+	n := noise(3)
+	s := make([]float64, sampleRate*3/2)
+	lp := 0.0
+	for i := range s {
+		t := float64(i) / sampleRate
+		lp += (n.next() - lp) * 0.05
+		groan := math.Sin(2 * math.Pi * (110*t - 25*t*t))
+		// The bubbles rise every tenth of a second.
+		b := math.Mod(t, 0.1)
+		bubble := math.Sin(2*math.Pi*(600+4000*b)*b) * math.Exp(-b/0.02) * (1 - t/1.5)
+		s[i] = (0.5*groan+2*lp)*envelope(t, 0.02, 0.5) + 0.2*bubble
+	}
+	return s
+}
+
+// melody plays the notes given by their frequencies, every one for the duration.
+func melody(notes []float64, dur float64) []float64 {
+	s := make([]float64, int(float64(len(notes))*dur*sampleRate+sampleRate/2))
+	for k, f := range notes {
+		start := int(float64(k) * dur * sampleRate)
+		for i := 0; start+i < len(s); i++ {
+			t := float64(i) / sampleRate
+			s[start+i] += 0.3 * tone(f, t) * envelope(t, 0.01, dur)
+		}
+	}
+	return s
+}
+
+func victory() []float64 {
+	return melody([]float64{523.25, 659.25, 783.99, 1046.5, 783.99, 1046.5}, 0.12)
+}
+
+func defeat() []float64 {
+	return melody([]float64{392, 329.63, 261.63, 196}, 0.25)
+}
+
+// music is the calm loop of the bass and the arpeggios over four chords.
+func music() []float64 {
+	const beat = 0.25
+	chords := [][]float64{
+		{110, 220, 261.63, 329.63},    // Am
+		{87.31, 174.61, 220, 261.63},  // F
+		{130.81, 196, 261.63, 329.63}, // C
+		{98, 196, 246.94, 293.66},     // G
+	}
+	s := make([]float64, int(4*4*2*beat*sampleRate))
+	for c, chord := range chords {
+		for step := 0; step < 8; step++ {
+			start := int((float64(c*8+step) * beat) * sampleRate)
+			f := chord[1+step%3]
+			if step >= 4 {
+				f = chord[1+(step+1)%3] * 2
+			}
+			for i := 0; float64(i) < beat*sampleRate && start+i < len(s); i++ {
+				t := float64(i) / sampleRate
+				s[start+i] += 0.12 * math.Sin(2*math.Pi*f*t) * envelope(t, 0.01, 0.2)
+				if step%4 == 0 {
+					s[start+i] += 0.2 * math.Sin(2*math.Pi*chord[0]*t) * envelope(t, 0.02, 0.6)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// writeWAV writes the samples as the 16-bit mono WAV file.
+func writeWAV(name string, samples []float64) {
+	var data bytes.Buffer
+	for _, v := range samples {
+		binary.Write(&data, binary.LittleEndian, int16(math.Max(-1, math.Min(1, v))*32767))
+	}
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+data.Len()))
+	b.WriteString("WAVEfmt ")
+	// The PCM format of one channel, 2 bytes per sample.
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(sampleRate), uint32(sampleRate * 2), uint16(2), uint16(16)} {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(data.Len()))
+	b.Write(data.Bytes())
+	if err := os.WriteFile(name, b.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	writeWAV("miss.wav", miss())
+	writeWAV("hit.wav", hit())
+	writeWAV("sink.wav", sink())
+	writeWAV("victory.wav", victory())
+	writeWAV("defeat.wav", defeat())
+	writeWAV("music.wav", music())
+}
@@ -0,0 +1,28 @@
+// sounds embed the sounds of the game into Go.
+package sounds
+
+import (
+	_ "embed"
+)
+
+//go:generate go run ./gen
+
+var (
+	//go:embed miss.wav
+	Miss []byte
+
+	//go:embed hit.wav
+	Hit []byte
+
+	//go:embed sink.wav
+	Sink []byte
+
+	//go:embed victory.wav
+	Victory []byte
+
+	//go:embed defeat.wav
+	Defeat []byte
+
+	//go:embed music.wav
+	Music []byte
+)
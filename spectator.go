@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// spectatorViews are the perspectives the spectators may choose,
+// as the viewers of the SideSelf and the SidePeer boards.
+var spectatorViews = map[string][2]Side{
+	"neutral": {SidePeer, SideSelf}, // both boards in the mist.
+	"you":     {SideSelf, SideSelf}, // as the player sees the game.
+	"peer":    {SidePeer, SidePeer}, // as the peer sees the game.
+	"full":    {SideSelf, SidePeer}, // all the ships revealed.
+}
+
+// snapshot is the game at some moment, kept for the delayed broadcast.
+type snapshot struct {
+	At      time.Time
+	Views   [2][2]*View // by the board and the viewer.
+	Turn    string
+	Over    bool
+	Message string
+}
+
+type apiView struct {
+	Rows  []string `json:"rows"`
+	Ships []string `json:"ships"` // like "A1-A4".
+	Scans []string `json:"scans"` // like "C5 contact" or "C5 clear stale".
+}
+
+type apiWatch struct {
+	ID      string     `json:"id"`
+	View    string     `json:"view"`
+	Delay   string     `json:"delay"`
+	Turn    string     `json:"turn"`
+	Over    bool       `json:"over"`
+	Message string     `json:"message"`
+	Boards  [2]apiView `json:"boards"`
+}
+
+// snap keeps the current state of the game for the spectators.
+// The snapshots older than the delay are dropped, except the latest of them.
+func (sg *serverGame) snap(delay time.Duration) {
+	g := sg.game
+	st := sg.state("")
+	sn := snapshot{At: time.Now(), Turn: st.Turn, Over: st.Over, Message: st.Message}
+	for board, b := range g.Boards {
+		for viewer := range sn.Views[board] {
+			sn.Views[board][viewer] = b.view(Side(viewer))
+		}
+	}
+	sg.snapshots = append(sg.snapshots, sn)
+	for len(sg.snapshots) > 1 && time.Since(sg.snapshots[1].At) > delay {
+		sg.snapshots = sg.snapshots[1:]
+	}
+}
+
+// watch returns the game as the spectators see it, delayed.
+func (s *Server) watch(r *http.Request) (any, error) {
+	name := r.URL.Query().Get("view")
+	if name == "" {
+		name = "neutral"
+	}
+	viewers, ok := spectatorViews[name]
+	if !ok {
+		return nil, badRequest("unknown view %q, want neutral, you, peer or full", name)
+	}
+	if name != "neutral" && s.Delay <= 0 {
+		// Otherwise the player could watch the ships of the opponent.
+		return nil, &apiError{http.StatusForbidden, fmt.Sprintf("the %s view needs the broadcast delay", name)}
+	}
+	sg, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sg.mu.Unlock()
+	// The latest snapshot old enough.
+	sn := sg.snapshots[0]
+	if time.Since(sn.At) < s.Delay {
+		return nil, conflict("the broadcast starts in %v", (s.Delay - time.Since(sn.At)).Round(time.Second))
+	}
+	for _, next := range sg.snapshots[1:] {
+		if time.Since(next.At) < s.Delay {
+			break
+		}
+		sn = next
+	}
+	w := &apiWatch{
+		ID:      r.PathValue("id"),
+		View:    name,
+		Delay:   s.Delay.String(),
+		Turn:    sn.Turn,
+		Over:    sn.Over,
+		Message: sn.Message,
+	}
+	for board, viewer := range viewers {
+		w.Boards[board] = encodeView(sn.Views[board][viewer])
+	}
+	return w, nil
+}
+
+func encodeView(v *View) apiView {
+	av := apiView{Rows: viewRows(v), Ships: []string{}, Scans: []string{}}
+	for _, s := range v.Fleet {
+		av.Ships = append(av.Ships, fmt.Sprintf("%s-%s", s.Cells[0], s.Cells[len(s.Cells)-1]))
+	}
+	for _, sc := range v.Scans {
+		scan := fmt.Sprintf("%s clear", sc.Center)
+		if sc.Found {
+			scan = fmt.Sprintf("%s contact", sc.Center)
+		}
+		if sc.Stale {
+			scan += " stale"
+		}
+		av.Scans = append(av.Scans, scan)
+	}
+	return av
+}
+
+func decodeView(side Side, av apiView) (*View, error) {
+	glyphCells := make(map[byte]Cell)
+	for c, glyph := range cellGlyphs {
+		if c != CellOily {
+			glyphCells[glyph] = c
+		}
+	}
+	v := &View{Side: side}
+	if len(av.Rows) != Ncells {
+		return nil, fmt.Errorf("got %d rows, want %d", len(av.Rows), Ncells)
+	}
+	for _, row := range av.Rows {
+		if len(row) != Ncells {
+			return nil, fmt.Errorf("bad row %q", row)
+		}
+		cells := make([]Cell, Ncells)
+		for x := range cells {
+			cells[x] = glyphCells[row[x]]
+		}
+		v.Cells = append(v.Cells, cells)
+	}
+	for i, s := range av.Ships {
+		ends := strings.Split(s, "-")
+		if len(ends) != 2 {
+			return nil, fmt.Errorf("bad ship %q", s)
+		}
+		p0, err0 := parseXY(ends[0])
+		p1, err1 := parseXY(ends[1])
+		if err0 != nil || err1 != nil {
+			return nil, fmt.Errorf("bad ship %q", s)
+		}
+		v.Fleet = append(v.Fleet, NewShip(i+1, p0, p1))
+	}
+	for _, s := range av.Scans {
+		f := strings.Fields(s)
+		if len(f) < 2 {
+			return nil, fmt.Errorf("bad scan %q", s)
+		}
+		xy, err := parseXY(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("bad scan %q", s)
+		}
+		v.Scans = append(v.Scans, Scan{Center: xy, Found: f[1] == "contact", Stale: len(f) > 2 && f[2] == "stale"})
+	}
+	return v, nil
+}
+
+// Spectator watches the game served by the HTTP API in the window.
+// The boards are drawn by the Game, which does not play itself.
+type Spectator struct {
+	Game    *Game
+	URL     string // of the watched game, like http://localhost:8080/games/1/watch?view=neutral
+	Chat    *Chat
+	views   [2]*View
+	message string
+	updates chan *apiWatch
+	errors  chan error
+}
+
+// NewSpectator returns the spectator of the game, speaking the language.
+func NewSpectator(gameURL, view, name string, lang *Lang) *Spectator {
+	s := &Spectator{
+		Game:    NewGame(Rules{}, nil),
+		URL:     strings.TrimRight(gameURL, "/") + "/watch?view=" + url.QueryEscape(view),
+		Chat:    NewChat(gameURL, name),
+		updates: make(chan *apiWatch),
+		errors:  make(chan error),
+	}
+	s.Game.Lang = lang
+	// The spectator only talks.
+	s.Game.Keymap = Keymap{ControlChat: {ebiten.KeyEnter, ebiten.KeyBackquote}}
+	s.message = s.Game.tr("Connecting...")
+	go s.poll()
+	return s
+}
+
+// poll fetches the game every second.
+func (s *Spectator) poll() {
+	for ; ; time.Sleep(time.Second) {
+		var w apiWatch
+		if err := apiCall("GET", s.URL, nil, &w); err != nil {
+			s.errors <- err
+			continue
+		}
+		s.updates <- &w
+	}
+}
+
+func (s *Spectator) Update() error {
+	typing := s.Chat.Typing
+	if status := s.Chat.update(s.Game); status != "" {
+		s.message = status
+	}
+	if !typing && inpututil.IsKeyJustReleased(ebiten.KeyQ) {
+		return ebiten.Termination
+	}
+	select {
+	case w := <-s.updates:
+		for board, av := range w.Boards {
+			v, err := decodeView(Side(board), av)
+			if err != nil {
+				s.message = err.Error()
+				return nil
+			}
+			s.views[board] = v
+		}
+		s.message = s.Game.tr("%s view: %s", s.Game.tr(w.View), w.Message)
+		if w.Delay != "0s" {
+			s.message = s.Game.tr("%s view, %s behind: %s", s.Game.tr(w.View), w.Delay, w.Message)
+		}
+	case err := <-s.errors:
+		s.message = err.Error()
+	default:
+	}
+	return nil
+}
+
+func (s *Spectator) Draw(screen *ebiten.Image) {
+	g := s.Game
+	if g.cellImage == nil {
+		g.cellImage = ebiten.NewImage(cellSize, cellSize)
+	}
+	g.drawBackground(screen)
+	for i, v := range s.views {
+		if v != nil {
+			g.Boards[i].draw(screen, v)
+		}
+	}
+	g.drawNumbers(screen)
+	g.drawMessage(screen, s.message)
+	_, h := g.Grid.pixelSize(g.Rules.Weapons)
+	s.Chat.draw(g, screen, g.Grid.Left, g.Grid.Top+h)
+}
+
+func (s *Spectator) Layout(oW, oH int) (int, int) {
+	return s.Game.fit(oW, oH, chatHeight())
+}
@@ -0,0 +1,57 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Speech says the text aloud with the text-to-speech program of the system.
+type Speech struct {
+	args  []string
+	stdin bool // whether the text is given on the standard input rather than as the last argument.
+	cmd   *exec.Cmd
+}
+
+// speechPrograms are tried in order: Linux, macOS and then Windows.
+var speechPrograms = []Speech{
+	{args: []string{"espeak-ng"}},
+	{args: []string{"espeak"}},
+	{args: []string{"spd-say", "--wait"}},
+	{args: []string{"say"}},
+	{args: []string{"powershell", "-NoProfile", "-Command",
+		"Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"}, stdin: true},
+}
+
+// NewSpeech finds the text-to-speech program of the system.
+func NewSpeech() (*Speech, error) {
+	for _, s := range speechPrograms {
+		if _, err := exec.LookPath(s.args[0]); err == nil {
+			return &s, nil
+		}
+	}
+	return nil, errors.New("no text-to-speech program, like espeak-ng, spd-say or say")
+}
+
+// say starts saying the text, and stops saying the previous one.
+func (s *Speech) say(text string) {
+	if s.cmd != nil {
+		// It may have finished already.
+		s.cmd.Process.Kill()
+	}
+	args := s.args
+	if !s.stdin {
+		args = append(args[:len(args):len(args)], text)
+	}
+	s.cmd = exec.Command(args[0], args[1:]...)
+	if s.stdin {
+		s.cmd.Stdin = strings.NewReader(text)
+	}
+	if err := s.cmd.Start(); err != nil {
+		s.cmd = nil
+		return
+	}
+	go s.cmd.Wait()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// Speech says the text aloud with the speech synthesis of the browser.
+type Speech struct {
+	synth js.Value
+}
+
+// NewSpeech finds the speech synthesis of the browser.
+func NewSpeech() (*Speech, error) {
+	synth := js.Global().Get("speechSynthesis")
+	if !synth.Truthy() {
+		return nil, errors.New("no speech synthesis in the browser")
+	}
+	return &Speech{synth}, nil
+}
+
+// say starts saying the text, and stops saying the previous one.
+func (s *Speech) say(text string) {
+	s.synth.Call("cancel")
+	s.synth.Call("speak", js.Global().Get("SpeechSynthesisUtterance").New(text))
+}
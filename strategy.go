@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Action is what a side does on its turn: fires the weapon at the target,
+// or moves the ship at the target by one cell in the direction.
+type Action struct {
+	Weapon Weapon
+	Target XY
+	Move   bool
+	Dir    XY
+}
+
+// String returns the action the way it is typed in the command line, like "radar C5" or "move C5 up".
+func (a Action) String() string {
+	if !a.Move {
+		return fmt.Sprintf("%s %s", strings.ToLower(a.Weapon.String()), a.Target)
+	}
+	for name, d := range cliDirections {
+		if d == a.Dir {
+			return fmt.Sprintf("move %s %s", a.Target, name)
+		}
+	}
+	// The opponent does not know which ship has moved.
+	return "move"
+}
+
+// Strategy plays the peer.
+// It never sees the Game nor the boards, only the views of them, so that it cannot
+// learn what the side may not know. Own board is seen whole, the opponent board
+// through the mist.
+type Strategy interface {
+	// Place puts the fleet on own board, where the islands are already placed.
+	Place(yard Shipyard) error
+	// Act chooses the action knowing own board, the opponent board view, and a copy of own weapons.
+	// It may run in the background, so it must not touch the game.
+	// The ship at the cell may move by one cell in the direction if canMove says so.
+	Act(own, v *View, a *Arsenal, canMove func(at, dir XY) bool) (Action, error)
+	// Observe reports the boards after the action of either side.
+	Observe(own bool, act Action, mine, theirs *View)
+	// End reports the outcome of the game: "win", "lose" or "stopped".
+	End(outcome string) error
+}
+
+// AIStrategy is the built-in strategy.
+type AIStrategy struct {
+	Rules Rules
+	// Search chooses where to look for the ships, e.g. huntLargestStrategy.
+	Search func(r *rand.Rand, v *View) (XY, error)
+	Rand   *rand.Rand
+}
+
+func NewAIStrategy(rules Rules) *AIStrategy {
+	return &AIStrategy{
+		Rules:  rules,
+		Search: huntLargestStrategy,
+		// Search: uniformStrategy,
+		Rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (s *AIStrategy) Place(yard Shipyard) error {
+	return yard.AddRandomShips()
+}
+
+func (s *AIStrategy) Act(own, v *View, a *Arsenal, canMove func(at, dir XY) bool) (Action, error) {
+	if s.Rules.Moving {
+		if act, ok := chooseMove(s.Rand, own, canMove); ok {
+			return act, nil
+		}
+	}
+	if xy, ok := v.fireCell(); ok {
+		// The weapon might have hit not the targeted cell.
+		xys := huntShipMore(v, xy)
+		if len(xys) == 0 {
+			return Action{}, fmt.Errorf("cannot find next hit point after %s", xy)
+		}
+		return Action{Target: xys[s.Rand.Intn(len(xys))]}, nil
+	}
+	if !s.Rules.Weapons {
+		xy, err := s.Search(s.Rand, v)
+		return Action{Target: xy}, err
+	}
+	return s.weaponStrategy(v, a)
+}
+
+func (s *AIStrategy) Observe(own bool, act Action, mine, theirs *View) {}
+
+func (s *AIStrategy) End(outcome string) error {
+	return nil
+}
+
+// checkShot returns the error if the side may not fire as chosen by its strategy.
+func (g *Game) checkShot(side Side, act Action) error {
+	if !inBoard(act.Target) {
+		return fmt.Errorf("bad target %v", act.Target)
+	}
+	if act.Weapon != WeaponShot && !g.Rules.Weapons {
+		return fmt.Errorf("weapons are not allowed")
+	}
+	if act.Weapon < 0 || act.Weapon >= numWeapons || !g.Arsenals[side].ready(act.Weapon) {
+		return fmt.Errorf("weapon %d is not ready", act.Weapon)
+	}
+	return nil
+}
+
+// weaponStrategy chooses both the weapon and the target.
+func (s *AIStrategy) weaponStrategy(v *View, a *Arsenal) (Action, error) {
+	if contacts := v.contacts(); len(contacts) > 0 {
+		// Finish the radar contact.
+		act := Action{Weapon: WeaponShot}
+		cellWeight, err := largestShipWeights(v)
+		if err != nil {
+			return act, err
+		}
+		if act.Target, err = hitHeaviest(s.Rand, cellWeight, contacts); err != nil {
+			act.Target = contacts[s.Rand.Intn(len(contacts))]
+		}
+		if a.ready(WeaponCluster) {
+			act.Weapon = WeaponCluster
+		}
+		return act, nil
+	}
+	target, err := s.Search(s.Rand, v)
+	if err != nil {
+		return Action{}, err
+	}
+	act := Action{Weapon: WeaponShot, Target: target}
+	unknown := 0
+	rowUnknown := 0
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if v.unknown(XY{x, y}) {
+				unknown++
+				if y == target.Y {
+					rowUnknown++
+				}
+			}
+		}
+	}
+	switch {
+	case a.ready(WeaponRadar) && unknown > Ncells*Ncells/2:
+		act.Weapon = WeaponRadar
+	case a.ready(WeaponTorpedo) && rowUnknown >= Ncells/2:
+		act.Weapon = WeaponTorpedo
+	case a.ready(WeaponCluster):
+		act.Weapon = WeaponCluster
+	}
+	return act, nil
+}
+
+// chooseMove decides whether to move a ship instead of firing.
+// It tries to escape the radar contacts, and sometimes moves just in case.
+func chooseMove(r *rand.Rand, own *View, canMove func(at, dir XY) bool) (Action, bool) {
+	var ships []*Ship
+	for _, sc := range own.Scans {
+		if !sc.Found || sc.Stale {
+			continue
+		}
+		for _, xy := range weaponArea(WeaponRadar, SideSelf, sc.Center) {
+			if s := own.shipAt(xy); s != nil && !slices.Contains(ships, s) {
+				ships = append(ships, s)
+			}
+		}
+	}
+	if len(ships) == 0 {
+		if r.Intn(10) != 0 {
+			return Action{}, false
+		}
+		ships = slices.Clone(own.Fleet)
+	}
+	r.Shuffle(len(ships), func(i, j int) {
+		ships[i], ships[j] = ships[j], ships[i]
+	})
+	ds := []XY{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+	r.Shuffle(len(ds), func(i, j int) {
+		ds[i], ds[j] = ds[j], ds[i]
+	})
+	for _, s := range ships {
+		for _, d := range ds {
+			if canMove(s.Cells[0], d) {
+				return Action{Move: true, Target: s.Cells[0], Dir: d}, true
+			}
+		}
+	}
+	return Action{}, false
+}
+
+// uniformStrategy uniformly choose a random cell to hit.
+func uniformStrategy(r *rand.Rand, v *View) (XY, error) {
+	// The previous attempt was a miss, or the ship was sunk.
+	xy := XY{r.Intn(Ncells), r.Intn(Ncells)}
+	for i := 0; i < Ncells*Ncells; i++ {
+		if v.unknown(xy) {
+			return xy, nil
+		}
+		// check the next cell
+		xy.X++
+		if xy.X >= Ncells {
+			xy.X = 0
+			xy.Y++
+			if xy.Y >= Ncells {
+				xy.Y = 0
+			}
+		}
+	}
+	// All cells are not suitable!
+	return xy, fmt.Errorf("all cells are hit already")
+}
+
+// huntLargestStrategy hunts for the largest ship.
+func huntLargestStrategy(r *rand.Rand, v *View) (XY, error) {
+	cellWeight, err := largestShipWeights(v)
+	if err != nil {
+		return XY{}, err
+	}
+	return hitHeaviest(r, cellWeight, nil)
+}
+
+// largestShipWeights returns for every cell the number of ways
+// the largest remaining ship can be placed over it.
+func largestShipWeights(v *View) (map[XY]int, error) {
+	largest := maxShipSize
+	for ; largest > 0; largest-- {
+		if v.Ships[largest-1] > 0 {
+			break
+		}
+	}
+	if largest <= 0 {
+		return nil, fmt.Errorf("could not determine largest ship")
+	}
+	cellWeight := make(map[XY]int)
+	markSlice := func(i0, i1 int, f func(i, w int)) {
+		if i0 == -1 {
+			return
+		}
+		if i1-i0 < largest {
+			return
+		}
+		// 0123456 <-- indices
+		// 123321  <-- weight
+		// log.Printf("markSlice(%d, %d)", i0, i1)
+		for i := i0; i < i1; i++ {
+			w := largest
+			if w1 := i - i0 + 1; w1 < w {
+				w = w1
+			}
+			if w2 := i1 - i; w2 < w {
+				w = w2
+			}
+			f(i, w)
+		}
+	}
+	// Scan along X.
+	for y := 0; y < Ncells; y++ {
+		xStart := -1
+		x := 0
+		fx := func(i, w int) {
+			xy := XY{i, y}
+			w0 := cellWeight[xy]
+			cellWeight[xy] = w0 + w
+			// log.Printf("weight %s: %d + %d -> %d", xy, w0, w, cellWeight[xy])
+		}
+		for ; x < Ncells; x++ {
+			if v.unknown(XY{x, y}) {
+				if xStart == -1 {
+					xStart = x
+				}
+			} else {
+				markSlice(xStart, x, fx)
+				xStart = -1
+			}
+		}
+		markSlice(xStart, x, fx)
+	}
+	// Scan along Y.
+	for x := 0; x < Ncells; x++ {
+		yStart := -1
+		y := 0
+		fy := func(i, w int) {
+			xy := XY{x, i}
+			w0 := cellWeight[xy]
+			cellWeight[xy] = w0 + w
+			// log.Printf("weight %s: %d + %d -> %d", xy, w0, w, cellWeight[xy])
+		}
+		for ; y < Ncells; y++ {
+			if v.unknown(XY{x, y}) {
+				if yStart == -1 {
+					yStart = y
+				}
+			} else {
+				markSlice(yStart, y, fy)
+				yStart = -1
+			}
+		}
+		markSlice(yStart, y, fy)
+	}
+	return cellWeight, nil
+}
+
+// hitHeaviest returns one of the cells with the max weight.
+// If only is not empty, just these cells are considered.
+func hitHeaviest(r *rand.Rand, cellWeight map[XY]int, only []XY) (XY, error) {
+	maxW := 0
+	var cells []XY
+	for xy, w := range cellWeight {
+		if len(only) > 0 && !slices.Contains(only, xy) {
+			continue
+		}
+		if w < maxW {
+			continue
+		}
+		if w > maxW {
+			cells = cells[:0]
+			maxW = w
+		}
+		cells = append(cells, xy)
+	}
+	// log.Printf("total suitable cells: %d; maxW(%d) cells(%d): %v", len(cellWeight), maxW, len(cells), cells)
+	if len(cells) == 0 {
+		return XY{}, fmt.Errorf("cannot find cells for the largest ship")
+	}
+	// The map order is random, sort the cells for the seeded games to repeat.
+	slices.SortFunc(cells, func(a, b XY) int {
+		if a.Y != b.Y {
+			return a.Y - b.Y
+		}
+		return a.X - b.X
+	})
+	i := r.Intn(len(cells))
+	return cells[i], nil
+}
+
+// huntShipMore returns the cells to hit to finish the damaged ship at the fire cell.
+func huntShipMore(v *View, fire XY) []XY {
+	only := false
+	check := func(xys *[]XY, xy XY) bool {
+		if v.Cells[xy.Y][xy.X] == CellFire {
+			only = true
+			return true
+		}
+		if v.unknown(xy) {
+			*xys = append(*xys, xy)
+		}
+		return false
+	}
+	xs := make([]XY, 0, 4)
+	for dx := -1; dx < 2; dx += 2 {
+		for x := fire.X + dx; x >= 0 && x < Ncells && check(&xs, XY{x, fire.Y}); x += dx {
+		}
+	}
+	if only {
+		return xs
+	}
+	ys := make([]XY, 0, 4)
+	for dy := -1; dy < 2; dy += 2 {
+		for y := fire.Y + dy; y >= 0 && y < Ncells && check(&ys, XY{fire.X, y}); y += dy {
+		}
+	}
+	if only {
+		return ys
+	}
+	return append(xs, ys...)
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bukind/seabattle2/fonts"
+	"github.com/bukind/seabattle2/themes"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Shape is the mark drawn in the middle of the cell.
+type Shape string
+
+const (
+	ShapeNone   Shape = ""
+	ShapeCircle Shape = "circle"
+	ShapeRing   Shape = "ring"
+	ShapeCross  Shape = "cross"
+	ShapeSquare Shape = "square"
+)
+
+// Color is the color written like "#rrggbb" or "#rrggbbaa" in the theme files.
+type Color color.RGBA
+
+// CellStyle is how the cell is drawn.
+type CellStyle struct {
+	Fill   Color   `json:"fill"`
+	Mark   Color   `json:"mark"`
+	Shape  Shape   `json:"shape"`
+	Size   float32 `json:"size"`   // of the mark, as the radius relative to the cell size.
+	Hatch  Color   `json:"hatch"`  // of the diagonal lines over the fill, if any.
+	Sprite string  `json:"sprite"` // the PNG image drawn over the fill instead of the mark.
+}
+
+// Theme is the look of the game: the palette, the cells and the font.
+type Theme struct {
+	Name       string             `json:"name"`
+	Base       string             `json:"base"` // the theme changed by this one.
+	Font       string             `json:"font"` // one of fontFiles.
+	Background Color              `json:"background"`
+	Lines      Color              `json:"lines"` // between the cells of the boards.
+	Text       Color              `json:"text"`
+	Cursor     Color              `json:"cursor"`
+	Hull       Color              `json:"hull"`    // the outlines of the ships.
+	Contact    Color              `json:"contact"` // the radar scans which found ships.
+	Clear      Color              `json:"clear"`   // the radar scans which did not.
+	Recent     Color              `json:"recent"`  // the frames of the last shots.
+	Outline    Color              `json:"outline"` // around every cell, if any.
+	Cells      map[Cell]CellStyle `json:"cells"`
+
+	sprites map[Cell]image.Image
+	images  map[Cell]*ebiten.Image // the sprites ready for drawing.
+}
+
+var (
+	// cellKinds are the names of the cells in the theme files.
+	cellKinds = [...]string{
+		CellEmpty: "empty",
+		CellMiss:  "miss",
+		CellMist:  "mist",
+		CellShip:  "ship",
+		CellFire:  "fire",
+		CellSunk:  "sunk",
+		CellOily:  "oily",
+		CellMine:  "mine",
+		CellBlast: "blast",
+		CellRock:  "rock",
+	}
+
+	// fontFiles are the embedded fonts to choose from.
+	fontFiles = map[string][]byte{
+		"sans":    fonts.PTSansRegular,
+		"bold":    fonts.PTSansBold,
+		"narrow":  fonts.PTSansNarrow,
+		"caption": fonts.PTSansCaption,
+	}
+	fontSources = map[string]*text.GoTextFaceSource{}
+
+	// themeList has the built-in themes first, then the themes of the user.
+	themeList = builtinThemes()
+)
+
+func (c Cell) MarshalText() ([]byte, error) {
+	if int(c) >= len(cellKinds) {
+		return nil, fmt.Errorf("unknown cell %d", int(c))
+	}
+	return []byte(cellKinds[c]), nil
+}
+
+func (c *Cell) UnmarshalText(b []byte) error {
+	for i, name := range cellKinds {
+		if name == string(b) {
+			*c = Cell(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown cell %q", b)
+}
+
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)), nil
+}
+
+func (c *Color) UnmarshalText(b []byte) error {
+	s := string(b)
+	var r, g, bl, a uint8
+	a = 0xff
+	var err error
+	switch len(s) {
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &bl)
+	case 9:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &r, &g, &bl, &a)
+	default:
+		err = errors.New("want #rrggbb or #rrggbbaa")
+	}
+	if err != nil {
+		return fmt.Errorf("bad color %q: %w", s, err)
+	}
+	*c = Color{r, g, bl, a}
+	return nil
+}
+
+// fontSource returns the embedded font, parsed on the first use.
+func fontSource(name string) *text.GoTextFaceSource {
+	if s, ok := fontSources[name]; ok {
+		return s
+	}
+	s, err := text.NewGoTextFaceSource(bytes.NewReader(fontFiles[name]))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fontSources[name] = s
+	return s
+}
+
+// builtinThemes returns the themes embedded into the game, sorted by the file name.
+func builtinThemes() []*Theme {
+	names, err := fs.Glob(themes.Files, "*.json")
+	if err != nil {
+		panic(err)
+	}
+	var list []*Theme
+	for _, name := range names {
+		b, err := themes.Files.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		t, err := parseTheme(list, b, strings.TrimSuffix(name, path.Ext(name)), "")
+		if err != nil {
+			panic(fmt.Sprintf("theme %s: %v", name, err))
+		}
+		list = append(list, t)
+	}
+	return list
+}
+
+// loadThemes adds the themes from the JSON files in the directory, if any.
+// A theme with the name of another one replaces it. The bad themes are skipped.
+func loadThemes(dir string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t, err := parseTheme(themeList, b, strings.TrimSuffix(filepath.Base(name), ".json"), dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bad theme %s: %w", name, err))
+			continue
+		}
+		if i := themeIndex(t.Name); i >= 0 {
+			themeList[i] = t
+		} else {
+			themeList = append(themeList, t)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseTheme reads the theme from JSON, named by the file unless it names itself.
+// The theme with a base only changes what it mentions in the base one.
+// The sprites are read from the directory.
+func parseTheme(list []*Theme, b []byte, name, dir string) (*Theme, error) {
+	// The cells mentioned in the file replace those of the base.
+	var own Theme
+	if err := json.Unmarshal(b, &own); err != nil {
+		return nil, err
+	}
+	t := &Theme{Name: name, Font: "sans", Cells: map[Cell]CellStyle{}, sprites: map[Cell]image.Image{}}
+	if own.Base != "" {
+		i := themeIndexIn(list, own.Base)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown base theme %q", own.Base)
+		}
+		*t = *list[i]
+		t.Name = name
+		t.Cells = maps.Clone(t.Cells)
+		t.sprites = maps.Clone(t.sprites)
+	}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	if _, ok := fontFiles[t.Font]; !ok {
+		return nil, fmt.Errorf("unknown font %q, want sans, bold, narrow or caption", t.Font)
+	}
+	for c, s := range own.Cells {
+		switch s.Shape {
+		case ShapeNone, ShapeCircle, ShapeRing, ShapeCross, ShapeSquare:
+		default:
+			return nil, fmt.Errorf("unknown shape %q of %s, want circle, ring, cross or square", s.Shape, cellKinds[c])
+		}
+		delete(t.sprites, c)
+		if s.Sprite == "" {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, s.Sprite))
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("bad sprite %s: %w", s.Sprite, err)
+		}
+		t.sprites[c] = img
+	}
+	t.images = map[Cell]*ebiten.Image{}
+	return t, nil
+}
+
+func themeIndex(name string) int {
+	return themeIndexIn(themeList, name)
+}
+
+func themeIndexIn(list []*Theme, name string) int {
+	for i, t := range list {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// findTheme returns the theme by its name, or the first one if there is none.
+func findTheme(name string) *Theme {
+	if i := themeIndex(name); i >= 0 {
+		return themeList[i]
+	}
+	return themeList[0]
+}
+
+// themesDir returns the directory of the themes of the user.
+func themesDir() (string, error) {
+	p, err := settingsPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), "themes"), nil
+}
+
+// face returns the font face of the theme of the size.
+// The letters missing in the font of the theme are taken from PT Sans.
+func (t *Theme) face(size float64) text.Face {
+	f := &text.GoTextFace{Source: fontSource(t.Font), Size: size}
+	if t.Font == "sans" {
+		return f
+	}
+	m, err := text.NewMultiFace(f, &text.GoTextFace{Source: fontSource("sans"), Size: size})
+	if err != nil {
+		return f
+	}
+	return m
+}
+
+// drawCellInto draws the cell filling the image.
+func (t *Theme) drawCellInto(c Cell, into *ebiten.Image) {
+	s := t.Cells[c]
+	into.Fill(color.RGBA(s.Fill))
+	if s.Hatch.A > 0 {
+		for x := -cellSizeF; x < cellSizeF; x += cellSizeF / 4 {
+			vector.StrokeLine(into, x, cellSizeF, x+cellSizeF, 0, 2, color.RGBA(s.Hatch), true)
+		}
+	}
+	if t.Outline.A > 0 {
+		defer vector.StrokeRect(into, 1, 1, cellSizeF-2, cellSizeF-2, 2, color.RGBA(t.Outline), false)
+	}
+	if img, ok := t.sprites[c]; ok {
+		sprite := t.images[c]
+		if sprite == nil {
+			sprite = ebiten.NewImageFromImage(img)
+			t.images[c] = sprite
+		}
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Scale(cellSize/float64(w), cellSize/float64(h))
+		into.DrawImage(sprite, opts)
+		return
+	}
+	col := color.RGBA(s.Mark)
+	c0 := cellSizeF / 2
+	r := cellSizeF * s.Size
+	switch s.Shape {
+	case ShapeCircle:
+		vector.DrawFilledCircle(into, c0, c0, r, col, true)
+	case ShapeRing:
+		vector.StrokeCircle(into, c0, c0, r, cellSizeF/10, col, true)
+	case ShapeCross:
+		vector.StrokeLine(into, c0-r, c0-r, c0+r, c0+r, cellSizeF/10, col, true)
+		vector.StrokeLine(into, c0-r, c0+r, c0+r, c0-r, cellSizeF/10, col, true)
+	case ShapeSquare:
+		vector.DrawFilledRect(into, c0-r, c0-r, 2*r, 2*r, col, false)
+	}
+}
+
+// drawBackground fills the screen and draws the lines between the cells of the boards.
+func (g *Game) drawBackground(screen *ebiten.Image) {
+	t := g.Theme
+	screen.Fill(color.RGBA(t.Background))
+	size := float32(cellPos(Ncells))
+	for side := SideSelf; side <= SidePeer; side++ {
+		x, y := g.Grid.cellOrigin(0, 0, side)
+		vector.DrawFilledRect(screen, x-cellBorder, y-cellBorder, size, size, color.RGBA(t.Lines), false)
+	}
+}
@@ -0,0 +1,11 @@
+// themes embed the built-in themes of the game into Go.
+package themes
+
+import (
+	"embed"
+)
+
+var (
+	//go:embed *.json
+	Files embed.FS
+)
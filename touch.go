@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	longPressTicks = gameTPS / 2
+	maxZoom        = 3
+)
+
+// cellNames describe the cells for the long press.
+var cellNames = map[Cell]string{
+	CellEmpty: "water",
+	CellMiss:  "miss",
+	CellMist:  "unknown",
+	CellShip:  "ship",
+	CellFire:  "ship on fire",
+	CellSunk:  "sunk ship",
+	CellOily:  "water",
+	CellMine:  "mine",
+	CellBlast: "exploded mine",
+	CellRock:  "island",
+}
+
+// Pinch is the zoom gesture by two touches.
+type Pinch struct {
+	Zoom   float64 // the distance between the touches times the zoom when started.
+	Start  float64 // the zoom when started.
+	Center [2]float64
+}
+
+func (g *Game) handleTouches() error {
+	g.activeTouches = inpututil.AppendJustPressedTouchIDs(g.activeTouches)
+	slices.Sort(g.activeTouches)
+	g.killedTouches = inpututil.AppendJustReleasedTouchIDs(g.killedTouches[:0])
+	slices.Sort(g.killedTouches)
+	// Remove killedTouches from activeTouches
+	i, j := 0, 0
+	for k := 0; k < len(g.killedTouches) && i < len(g.activeTouches); i++ {
+		if g.activeTouches[i] == g.killedTouches[k] {
+			// if the touch is released.
+			k++
+		} else {
+			// the touch is not released yet.
+			g.activeTouches[j] = g.activeTouches[i]
+			j++
+		}
+	}
+	g.activeTouches = append(g.activeTouches[:j], g.activeTouches[i:]...)
+	if g.Menu != nil {
+		// The boards are under the menu.
+		return nil
+	}
+	g.handleGestures()
+	if g.WhoseTurn != SideSelf || g.animating() {
+		return nil
+	}
+	// Draw cursor at the active touches on the peer board.
+	for _, t := range g.activeTouches {
+		tx, ty := ebiten.TouchPosition(t)
+		if !slices.Contains(g.spentTouches, t) && !g.ConfirmTaps && g.Grid.inBoard(tx, ty, SidePeer) {
+			g.CursorSelf = g.Grid.pos2Cell(tx, ty, SidePeer)
+		}
+	}
+	for _, t := range g.killedTouches {
+		if i := slices.Index(g.spentTouches, t); i >= 0 {
+			// The touch has been a long press or a pinch.
+			g.spentTouches = slices.Delete(g.spentTouches, i, i+1)
+			continue
+		}
+		tx, ty := inpututil.TouchPositionInPreviousTick(t)
+		if g.PlacingMines {
+			if g.Grid.inBoard(tx, ty, SideSelf) {
+				g.hideMine(g.Grid.pos2Cell(tx, ty, SideSelf))
+			}
+			continue
+		}
+		if g.Moving {
+			if !g.Grid.inBoard(tx, ty, SideSelf) {
+				continue
+			}
+			if err := g.handleMoveClick(g.Grid.pos2Cell(tx, ty, SideSelf)); err != nil {
+				return err
+			}
+			continue
+		}
+		if w, ok := g.weaponAt(tx, ty); ok && g.Rules.Weapons {
+			g.selectWeapon(w)
+			continue
+		}
+		if !g.Grid.inBoard(tx, ty, SidePeer) {
+			continue
+		}
+		xy := g.Grid.pos2Cell(tx, ty, SidePeer)
+		if g.ConfirmTaps && (!g.TapArmed || g.CursorSelf != xy) {
+			// The first tap only selects the cell.
+			g.CursorSelf, g.TapArmed = xy, true
+			g.Message = g.tr("Tap %s again to fire", g.Lang.cell(xy))
+			continue
+		}
+		g.CursorSelf, g.TapArmed = xy, false
+		if err := g.selfShoot(); err != nil {
+			return err
+		}
+		if g.WhoseTurn != SideSelf {
+			return nil
+		}
+	}
+	return nil
+}
+
+// handleGestures shows the cell under the long press, and zooms the boards by the pinch.
+// The touches of the gestures are spent, so that they do not fire when released.
+func (g *Game) handleGestures() {
+	if len(g.activeTouches) != 2 {
+		g.pinch = nil
+	}
+	switch len(g.activeTouches) {
+	case 1:
+		t := g.activeTouches[0]
+		if inpututil.TouchPressDuration(t) != longPressTicks || slices.Contains(g.spentTouches, t) {
+			return
+		}
+		tx, ty := ebiten.TouchPosition(t)
+		for side := SideSelf; side <= SidePeer; side++ {
+			if g.Grid.inBoard(tx, ty, side) {
+				g.Message = g.cellInfo(side, g.Grid.pos2Cell(tx, ty, side))
+				g.spentTouches = append(g.spentTouches, t)
+			}
+		}
+	case 2:
+		x0, y0 := ebiten.TouchPosition(g.activeTouches[0])
+		x1, y1 := ebiten.TouchPosition(g.activeTouches[1])
+		// The screen is scaled by the zoom, so the distances are scaled back.
+		dist := math.Hypot(float64(x1-x0), float64(y1-y0)) * g.zoom()
+		center := [2]float64{float64(x0+x1) / 2, float64(y0+y1) / 2}
+		if g.pinch == nil {
+			g.pinch = &Pinch{Zoom: dist, Start: g.zoom(), Center: center}
+			for _, t := range g.activeTouches {
+				if !slices.Contains(g.spentTouches, t) {
+					g.spentTouches = append(g.spentTouches, t)
+				}
+			}
+			return
+		}
+		if g.pinch.Zoom > 0 {
+			g.Zoom = min(max(g.pinch.Start*dist/g.pinch.Zoom, 1), maxZoom)
+		}
+		// Moving both touches drags the zoomed boards.
+		g.Pan[0] += center[0] - g.pinch.Center[0]
+		g.Pan[1] += center[1] - g.pinch.Center[1]
+		g.pinch.Center = center
+	}
+}
+
+// zoom returns the zoom of the boards, 1 when they fit the window.
+func (g *Game) zoom() float64 {
+	return max(g.Zoom, 1)
+}
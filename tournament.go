@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	eloInitial = 1500
+	eloK       = 16
+	// maxMatchActions stops the game where nobody can win, e.g. when ships keep moving.
+	maxMatchActions = 1000
+)
+
+// builtinStrategies are the search strategies of the built-in AI by name.
+var builtinStrategies = map[string]func(r *rand.Rand, v *View) (XY, error){
+	"hunt":    huntLargestStrategy,
+	"uniform": uniformStrategy,
+}
+
+// Entrant is a participant of the tournament: a built-in strategy or a bot.
+type Entrant struct {
+	Name    string
+	Command string // the bot command, empty for the built-in strategy.
+	Search  func(r *rand.Rand, v *View) (XY, error)
+	Rating  float64
+	Points  float64 // 1 for a win, 0.5 for a draw.
+	Wins    int
+	Losses  int
+	Draws   int
+}
+
+// Match is a game between two entrants without a human.
+type Match struct {
+	Seed     int64
+	Entrants [2]int // the indices of the entrants playing SideSelf and SidePeer.
+	Winner   Side   // -1 for a draw.
+	Actions  int
+	Lives    [2]int   // the remaining lives of the sides.
+	Error    string   // why the game was forfeited.
+	Notes    []string // the failures not changing the result, like ending the game.
+	Record   []string
+}
+
+// Tournament plays the games between all the entrants and rates them.
+type Tournament struct {
+	Rules    Rules
+	Entrants []*Entrant
+	Games    int // games per pairing, the sides are swapped every game.
+	Seed     int64
+	Timeout  time.Duration // for the bot replies.
+	Matches  []*Match
+}
+
+// NewTournament creates the tournament of the entrants given by names,
+// which are either the built-in strategies or the bot commands.
+func NewTournament(rules Rules, names []string, games int, seed int64, timeout time.Duration) (*Tournament, error) {
+	if len(names) < 2 {
+		return nil, fmt.Errorf("need at least 2 entrants, got %d", len(names))
+	}
+	t := &Tournament{
+		Rules:   rules,
+		Games:   games,
+		Seed:    seed,
+		Timeout: timeout,
+	}
+	for _, name := range names {
+		e := &Entrant{Name: name, Rating: eloInitial, Search: builtinStrategies[name]}
+		if e.Search == nil {
+			e.Command = name
+			args := strings.Fields(name)
+			e.Name = filepath.Base(args[len(args)-1])
+		}
+		// Make the names unique to tell the entrants apart.
+		for n := 2; slices.ContainsFunc(t.Entrants, func(o *Entrant) bool { return o.Name == e.Name }); n++ {
+			e.Name = fmt.Sprintf("%s#%d", strings.Split(e.Name, "#")[0], n)
+		}
+		t.Entrants = append(t.Entrants, e)
+	}
+	return t, nil
+}
+
+// strategy returns the new strategy of the entrant for one game.
+func (t *Tournament) strategy(e *Entrant, seed int64) (Strategy, error) {
+	if e.Command != "" {
+		return NewBotStrategy(e.Command, t.Rules, t.Timeout)
+	}
+	return &AIStrategy{
+		Rules:  t.Rules,
+		Search: e.Search,
+		Rand:   rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// RoundRobin plays every pair of the entrants.
+func (t *Tournament) RoundRobin() {
+	for i := range t.Entrants {
+		for j := i + 1; j < len(t.Entrants); j++ {
+			t.playPairing(i, j)
+		}
+	}
+}
+
+// Swiss plays the rounds, where the entrants with close points meet,
+// and nobody meets the same opponent twice if possible.
+// With the odd number of entrants, the last one without a bye yet gets it, worth a win.
+func (t *Tournament) Swiss(rounds int) {
+	met := make(map[[2]int]bool)
+	byes := make(map[int]bool)
+	for round := 0; round < rounds; round++ {
+		order := make([]int, len(t.Entrants))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			ea, eb := t.Entrants[a], t.Entrants[b]
+			if ea.Points != eb.Points {
+				return cmpFloat(eb.Points, ea.Points)
+			}
+			return cmpFloat(eb.Rating, ea.Rating)
+		})
+		if len(order)%2 == 1 {
+			k := len(order) - 1
+			for k > 0 && byes[order[k]] {
+				k--
+			}
+			byes[order[k]] = true
+			t.Entrants[order[k]].Points++
+			order = slices.Delete(order, k, k+1)
+		}
+		pairs := swissPairs(order, met)
+		if pairs == nil {
+			// No pairing avoids the rematches, so the neighbours meet again.
+			for k := 0; k+1 < len(order); k += 2 {
+				pairs = append(pairs, [2]int{order[k], order[k+1]})
+			}
+		}
+		for _, p := range pairs {
+			met[p] = true
+			met[[2]int{p[1], p[0]}] = true
+			t.playPairing(p[0], p[1])
+		}
+	}
+}
+
+// swissPairs splits the ordered entrants into the pairs, which have not met yet,
+// the closest in the order first. It returns nil if there are no such pairs.
+func swissPairs(order []int, met map[[2]int]bool) [][2]int {
+	if len(order) == 0 {
+		return [][2]int{}
+	}
+	i := order[0]
+	for k := 1; k < len(order); k++ {
+		j := order[k]
+		if met[[2]int{i, j}] {
+			continue
+		}
+		rest := slices.Delete(slices.Clone(order), k, k+1)[1:]
+		if pairs := swissPairs(rest, met); pairs != nil {
+			return append([][2]int{{i, j}}, pairs...)
+		}
+	}
+	return nil
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// playPairing plays the games between the entrants i and j, swapping the sides every game.
+func (t *Tournament) playPairing(i, j int) {
+	for n := 0; n < t.Games; n++ {
+		pair := [2]int{i, j}
+		if n%2 == 1 {
+			pair = [2]int{j, i}
+		}
+		m := t.playMatch(pair, t.Seed+int64(len(t.Matches)))
+		t.Matches = append(t.Matches, m)
+		t.rate(m)
+	}
+}
+
+// playMatch plays one game between the entrants, the first of them starts.
+func (t *Tournament) playMatch(pair [2]int, seed int64) *Match {
+	m := &Match{Seed: seed, Entrants: pair, Winner: -1}
+	g := NewGame(t.Rules, nil)
+	g.Rand = rand.New(rand.NewSource(seed))
+	var strategies [2]Strategy
+	defer func() {
+		for side, s := range strategies {
+			if s == nil {
+				continue
+			}
+			outcome := "stopped"
+			if m.Winner == Side(side) {
+				outcome = "win"
+			} else if m.Winner >= 0 {
+				outcome = "lose"
+			}
+			if err := s.End(outcome); err != nil {
+				// The result stands, the failure is only noted.
+				e := fmt.Sprintf("%s has failed to end: %v", t.Entrants[pair[side]].Name, err)
+				m.Notes = append(m.Notes, e)
+				m.Record = append(m.Record, "# "+e)
+			}
+		}
+	}()
+	forfeit := func(side Side, err error) *Match {
+		m.Winner = side.opponent()
+		m.Error = fmt.Sprintf("%s: %v", t.Entrants[pair[side]].Name, err)
+		m.Record = append(m.Record, "# forfeit by "+m.Error)
+		return m
+	}
+	m.Record = append(m.Record, fmt.Sprintf("# %s vs %s, seed %d", t.Entrants[pair[0]].Name, t.Entrants[pair[1]].Name, seed))
+	m.Record = append(m.Record, "# rules "+botRules(t.Rules))
+	for side, b := range g.Boards {
+		s, err := t.strategy(t.Entrants[pair[side]], seed+int64(side)+1)
+		if err != nil {
+			return forfeit(Side(side), err)
+		}
+		strategies[side] = s
+		if err := b.setup(30, s.Place); err != nil {
+			return forfeit(Side(side), err)
+		}
+	}
+	var sb strings.Builder
+	printViews(&sb, [2]*View{g.Boards[SideSelf].view(SideSelf), g.Boards[SidePeer].view(SidePeer)})
+	m.Record = append(m.Record, strings.Split(strings.TrimRight(sb.String(), "\n"), "\n")...)
+
+	side := SideSelf
+	for m.Actions < maxMatchActions {
+		m.Actions++
+		own, opp := g.Boards[side], g.Boards[side.opponent()]
+		a := *g.Arsenals[side]
+		act, err := strategies[side].Act(own.view(side), opp.view(side), &a, own.canMoveAt)
+		if err != nil {
+			return forfeit(side, err)
+		}
+		again := false
+		line := fmt.Sprintf("%d. %s: ", m.Actions, t.Entrants[pair[side]].Name)
+		if act.Move {
+			if err := g.strategyMove(side, act); err != nil {
+				return forfeit(side, err)
+			}
+			line += act.String()
+		} else {
+			if err := g.checkShot(side, act); err != nil {
+				return forfeit(side, err)
+			}
+			if again, err = g.shoot(side, act.Weapon, act.Target); err != nil {
+				return forfeit(side, err)
+			}
+			line += act.String()
+			if changes := g.History[len(g.History)-1].Results; len(changes) > 0 {
+				line += ": " + strings.Join(changes, ", ")
+			}
+		}
+		m.Record = append(m.Record, line)
+		strategies[side].Observe(true, act, own.view(side), opp.view(side))
+		if act.Move {
+			// The opponent does not know where the moved ship was.
+			act = Action{Move: true}
+		}
+		strategies[opp.Side].Observe(false, act, opp.view(opp.Side), own.view(opp.Side))
+		if g.Boards[SideSelf].Lives == 0 || g.Boards[SidePeer].Lives == 0 {
+			break
+		}
+		if !again {
+			side = g.nextTurn(side)
+		}
+	}
+	for s, b := range g.Boards {
+		m.Lives[s] = b.Lives
+		if b.Lives == 0 {
+			m.Winner = Side(s).opponent()
+		}
+	}
+	if m.Winner < 0 {
+		m.Record = append(m.Record, fmt.Sprintf("# draw after %d actions", m.Actions))
+	} else {
+		m.Record = append(m.Record, fmt.Sprintf("# %s wins with %d lives left", t.Entrants[pair[m.Winner]].Name, m.Lives[m.Winner]))
+	}
+	return m
+}
+
+// rate updates the points and the Elo ratings of the entrants after the match.
+func (t *Tournament) rate(m *Match) {
+	a, b := t.Entrants[m.Entrants[0]], t.Entrants[m.Entrants[1]]
+	score := 0.5
+	switch m.Winner {
+	case SideSelf:
+		score = 1
+		a.Wins++
+		b.Losses++
+	case SidePeer:
+		score = 0
+		a.Losses++
+		b.Wins++
+	default:
+		a.Draws++
+		b.Draws++
+	}
+	a.Points += score
+	b.Points += 1 - score
+	expected := 1 / (1 + math.Pow(10, (b.Rating-a.Rating)/400))
+	a.Rating += eloK * (score - expected)
+	b.Rating -= eloK * (score - expected)
+}
+
+// Report prints the rating table and the results of every pair.
+func (t *Tournament) Report(out io.Writer) {
+	ranked := slices.Clone(t.Entrants)
+	slices.SortStableFunc(ranked, func(a, b *Entrant) int {
+		return cmpFloat(b.Rating, a.Rating)
+	})
+	fmt.Fprintf(out, "%-4s %-20s %6s %6s %5s %5s %5s\n", "#", "entrant", "rating", "points", "won", "lost", "drawn")
+	for i, e := range ranked {
+		fmt.Fprintf(out, "%-4d %-20s %6.0f %6.1f %5d %5d %5d\n", i+1, e.Name, e.Rating, e.Points, e.Wins, e.Losses, e.Draws)
+	}
+	fmt.Fprintln(out)
+	type result struct{ wins, losses, draws int }
+	pairs := make(map[[2]int]*result)
+	var order [][2]int
+	for _, m := range t.Matches {
+		key, winner := m.Entrants, m.Winner
+		if key[0] > key[1] {
+			key = [2]int{key[1], key[0]}
+			if winner >= 0 {
+				winner = winner.opponent()
+			}
+		}
+		r, ok := pairs[key]
+		if !ok {
+			r = &result{}
+			pairs[key] = r
+			order = append(order, key)
+		}
+		switch winner {
+		case SideSelf:
+			r.wins++
+		case SidePeer:
+			r.losses++
+		default:
+			r.draws++
+		}
+	}
+	for _, key := range order {
+		r := pairs[key]
+		fmt.Fprintf(out, "%s vs %s: %d-%d", t.Entrants[key[0]].Name, t.Entrants[key[1]].Name, r.wins, r.losses)
+		if r.draws > 0 {
+			fmt.Fprintf(out, ", %d drawn", r.draws)
+		}
+		fmt.Fprintln(out)
+	}
+	for _, m := range t.Matches {
+		if m.Error != "" {
+			fmt.Fprintf(out, "game seed %d forfeited by %s\n", m.Seed, m.Error)
+		}
+		for _, n := range m.Notes {
+			fmt.Fprintf(out, "game seed %d: %s\n", m.Seed, n)
+		}
+	}
+}
+
+// interesting returns the number of matches, the most interesting first:
+// the closest ones, where the winner had the least lives left, then the longest ones.
+func (t *Tournament) interesting(n int) []*Match {
+	matches := slices.Clone(t.Matches)
+	closeness := func(m *Match) int {
+		if m.Winner < 0 || m.Error != "" {
+			// Draws and forfeits are rarely interesting.
+			return math.MaxInt
+		}
+		return m.Lives[m.Winner]
+	}
+	slices.SortStableFunc(matches, func(a, b *Match) int {
+		if ca, cb := closeness(a), closeness(b); ca != cb {
+			return ca - cb
+		}
+		return b.Actions - a.Actions
+	})
+	return matches[:min(n, len(matches))]
+}
+
+// SaveRecords writes the records of the n most interesting matches into the directory.
+func (t *Tournament) SaveRecords(dir string, n int) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i, m := range t.interesting(n) {
+		name := filepath.Join(dir, fmt.Sprintf("game-%02d-seed-%d.txt", i+1, m.Seed))
+		if err := os.WriteFile(name, []byte(strings.Join(m.Record, "\n")+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTournament plays the tournament as set by the flags and prints the results.
+func runTournament(rules Rules, names []string, format string, games, rounds int, seed int64, timeout time.Duration, records string, out io.Writer) error {
+	t, err := NewTournament(rules, names, games, seed, timeout)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "round-robin":
+		t.RoundRobin()
+	case "swiss":
+		t.Swiss(rounds)
+	default:
+		return fmt.Errorf("unknown tournament format %q, want round-robin or swiss", format)
+	}
+	t.Report(out)
+	if records != "" {
+		return t.SaveRecords(records, 3)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		ratings [2]float64
+		winner  Side
+		want    [2]float64
+		points  [2]float64
+	}{
+		{"even win", [2]float64{1500, 1500}, SideSelf, [2]float64{1508, 1492}, [2]float64{1, 0}},
+		{"even loss", [2]float64{1500, 1500}, SidePeer, [2]float64{1492, 1508}, [2]float64{0, 1}},
+		{"even draw", [2]float64{1500, 1500}, -1, [2]float64{1500, 1500}, [2]float64{0.5, 0.5}},
+		// The favourite expects 10 wins of 11 and gains little.
+		{"favourite wins", [2]float64{1900, 1500}, SideSelf, [2]float64{1900 + 16.0/11, 1500 - 16.0/11}, [2]float64{1, 0}},
+		{"underdog wins", [2]float64{1900, 1500}, SidePeer, [2]float64{1900 - 160.0/11, 1500 + 160.0/11}, [2]float64{0, 1}},
+		{"underdog draws", [2]float64{1500, 1900}, -1, [2]float64{1500 + 72.0/11, 1900 - 72.0/11}, [2]float64{0.5, 0.5}},
+	} {
+		tr := &Tournament{Entrants: []*Entrant{{Rating: tc.ratings[0]}, {Rating: tc.ratings[1]}}}
+		tr.rate(&Match{Entrants: [2]int{0, 1}, Winner: tc.winner})
+		for i, e := range tr.Entrants {
+			if math.Abs(e.Rating-tc.want[i]) > 1e-9 || e.Points != tc.points[i] {
+				t.Errorf("%s: entrant %d has rating %.3f and %v points, want %.3f and %v", tc.name, i, e.Rating, e.Points, tc.want[i], tc.points[i])
+			}
+		}
+		if a, b := tr.Entrants[0], tr.Entrants[1]; a.Wins != b.Losses || a.Losses != b.Wins || a.Draws != b.Draws || a.Wins+a.Losses+a.Draws != 1 {
+			t.Errorf("%s: got %+v and %+v", tc.name, a, b)
+		}
+	}
+}
+
+func TestSwissNoRematches(t *testing.T) {
+	for _, names := range [][]string{
+		{"hunt", "uniform", "hunt", "uniform"},
+		{"hunt", "uniform", "hunt", "uniform", "hunt"},
+	} {
+		tr, err := NewTournament(Rules{}, names, 1, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const rounds = 3
+		tr.Swiss(rounds)
+		met := make(map[[2]int]bool)
+		played := make([]int, len(names))
+		for _, m := range tr.Matches {
+			i, j := min(m.Entrants[0], m.Entrants[1]), max(m.Entrants[0], m.Entrants[1])
+			if met[[2]int{i, j}] {
+				t.Errorf("%d entrants: %s and %s meet again", len(names), tr.Entrants[i].Name, tr.Entrants[j].Name)
+			}
+			met[[2]int{i, j}] = true
+			played[i]++
+			played[j]++
+		}
+		if want := rounds * (len(names) / 2); len(tr.Matches) != want {
+			t.Errorf("%d entrants: %d matches, want %d", len(names), len(tr.Matches), want)
+		}
+		// Nobody gets the bye twice.
+		for i, n := range played {
+			if n < rounds-1 {
+				t.Errorf("%d entrants: %s has played %d games in %d rounds", len(names), tr.Entrants[i].Name, n, rounds)
+			}
+		}
+	}
+}
+
+func TestSwissPairs(t *testing.T) {
+	met := map[[2]int]bool{{2, 3}: true, {3, 2}: true}
+	// The closest pairs 0-1 and 2-3 would make a rematch.
+	if got, want := swissPairs([]int{0, 1, 2, 3}, met), [][2]int{{0, 2}, {1, 3}}; !slices.Equal(got, want) {
+		t.Errorf("got the pairs %v, want %v", got, want)
+	}
+	met[[2]int{0, 2}], met[[2]int{2, 0}] = true, true
+	met[[2]int{1, 2}], met[[2]int{2, 1}] = true, true
+	if got := swissPairs([]int{0, 1, 2, 3}, met); got != nil {
+		t.Errorf("got the pairs %v with everybody met by 2", got)
+	}
+}
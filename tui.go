@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// tuiKeys maps the terminal input to the controls.
+var tuiKeys = map[string]Control{
+	"\x1b[A": ControlUp,
+	"\x1b[B": ControlDown,
+	"\x1b[C": ControlRight,
+	"\x1b[D": ControlLeft,
+	"\x1b":   ControlCancel,
+	"\x03":   ControlQuit, // Ctrl-C
+	" ":      ControlFire,
+	"q":      ControlQuit,
+	"Q":      ControlQuit,
+	"u":      ControlUndo,
+	"U":      ControlUndo,
+	"m":      ControlMoving,
+	"M":      ControlMoving,
+	"h":      ControlHint,
+	"n":      ControlOrder,
+	"N":      ControlOrder,
+	"H":      ControlHint,
+	"1":      ControlWeapon,
+	"2":      ControlWeapon + 1,
+	"3":      ControlWeapon + 2,
+	"4":      ControlWeapon + 3,
+}
+
+// splitKeys splits the terminal input into the keys, as several of them may come
+// in one read, like a held arrow. The keys are the escape sequences like "\x1b[A",
+// the lone escape, and the single bytes.
+func splitKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		n := 1
+		if b[0] == '\x1b' && len(b) > 2 && b[1] == '[' {
+			// The sequence ends with the final byte from '@' to '~'.
+			for n = 2; n < len(b) && (b[n] < '@' || b[n] > '~'); n++ {
+			}
+			n = min(n+1, len(b))
+		}
+		keys = append(keys, string(b[:n]))
+		b = b[n:]
+	}
+	return keys
+}
+
+// runTUI plays the game in the terminal.
+func runTUI(g *Game) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("cannot switch terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+	out := bufio.NewWriter(os.Stdout)
+	// Hide the cursor while playing.
+	fmt.Fprint(out, "\x1b[?25l\x1b[2J")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h")
+		out.Flush()
+	}()
+
+	keys := make(chan Control)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, s := range splitKeys(buf[:n]) {
+				if k, ok := tuiKeys[s]; ok {
+					keys <- k
+				}
+			}
+		}
+	}()
+
+	defer g.finish()
+	if err := g.init(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(time.Second / gameTPS)
+	defer ticker.Stop()
+	for g.Error == nil {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				k = ControlQuit
+			}
+			before := g.choice()
+			if err := g.handleControl(k); err != nil {
+				g.Error = err
+			}
+			g.remember(before)
+		case <-ticker.C:
+			g.Tick++
+			if err := g.updatePeer(); err != nil {
+				g.Error = err
+			}
+		}
+		g.flushAnnouncements()
+		g.drawTUI(out)
+		out.Flush()
+	}
+	return nil
+}
+
+// tuiShapes are the glyphs of the marks of the cells.
+var tuiShapes = map[Shape]string{
+	ShapeCircle: "()",
+	ShapeRing:   "()",
+	ShapeCross:  "><",
+	ShapeSquare: "[]",
+}
+
+// tuiEvents is the number of the last shots shown under the boards.
+const tuiEvents = 3
+
+// tuiFleet draws the ships of the board like drawFleet, the sunk ones with crosses.
+func tuiFleet(b *Board) string {
+	var ships []string
+	for size := maxShipSize; size > 0; size-- {
+		for i := 0; i < fleetSize(size); i++ {
+			glyph := "#"
+			if i >= b.Ships[size-1] {
+				glyph = "x"
+			}
+			ships = append(ships, strings.Repeat(glyph, size))
+		}
+	}
+	return strings.Join(ships, " ")
+}
+
+// ansiColor returns the escape sequence setting the 24-bit color.
+func ansiColor(c color.RGBA, background bool) string {
+	layer := 38
+	if background {
+		layer = 48
+	}
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", layer, c.R, c.G, c.B)
+}
+
+// drawTUI draws both boards in the terminal, the same way Draw does in the window.
+func (g *Game) drawTUI(out *bufio.Writer) {
+	const reset = "\x1b[0m"
+	msg := g.Message
+	if g.Error != nil {
+		msg = g.Error.Error()
+	}
+	fmt.Fprintf(out, "\x1b[H%s\x1b[K\r\n\r\n", msg)
+	views := g.views()
+	var turns [2]*[Ncells][Ncells]int
+	var recent [2][]int
+	for side := range views {
+		turns[side] = g.shotTurns(Side(side))
+		recent[side] = g.recentShots(Side(side), recentMarks)
+	}
+	cursorSide, cursor := g.cursorArea()
+	blink := g.Tick%(gameTPS+1) < gameTPS/2
+	for y := 0; y < Ncells; y++ {
+		for side, v := range views {
+			fmt.Fprintf(out, "%c ", '1'+y)
+			for x := 0; x < Ncells; x++ {
+				style := g.Theme.Cells[v.Cells[y][x]]
+				glyph := "  "
+				if style.Hatch.A > 0 {
+					glyph = ansiColor(color.RGBA(style.Hatch), false) + "//"
+				}
+				if s, ok := tuiShapes[style.Shape]; ok {
+					if style.Shape == ShapeCircle && style.Size < 0.4 {
+						s = "<>"
+					}
+					glyph = ansiColor(color.RGBA(style.Mark), false) + s
+				}
+				if t := turns[side][y][x]; t > 0 && (g.ShowOrder || slices.Contains(recent[side], t)) {
+					// The turns are numbered like in the window, the recent ones in their color.
+					col := g.Theme.Text
+					if slices.Contains(recent[side], t) {
+						col = g.Theme.Recent
+					}
+					glyph = ansiColor(color.RGBA(col), false) + fmt.Sprintf("%2d", t%100)
+				}
+				bg := color.RGBA(style.Fill)
+				if Side(side) == cursorSide && blink && slices.Contains(cursor, XY{x, y}) {
+					bg = color.RGBA(g.Theme.Cursor)
+				}
+				fmt.Fprintf(out, "%s%s%s", ansiColor(bg, true), glyph, reset)
+			}
+			fmt.Fprint(out, "   ")
+		}
+		fmt.Fprint(out, "\x1b[K\r\n")
+	}
+	for range views {
+		fmt.Fprint(out, "  ")
+		for x := 0; x < Ncells; x++ {
+			fmt.Fprintf(out, "%c ", g.Lang.columns[x])
+		}
+		fmt.Fprint(out, "   ")
+	}
+	fmt.Fprint(out, "\x1b[K\r\n\r\n")
+	if g.Rules.Weapons {
+		a := g.Arsenals[SideSelf]
+		for w := Weapon(0); w < numWeapons; w++ {
+			label := fmt.Sprintf("%d:%s", w+1, a.status(g.Lang, w))
+			if w == a.Selected {
+				label = "[" + label + "]"
+			}
+			fmt.Fprintf(out, "%s  ", label)
+		}
+		fmt.Fprint(out, "\x1b[K\r\n")
+	}
+	fmt.Fprintf(out, "%s\x1b[K\r\n", g.turnText())
+	for side := SideSelf; side <= SidePeer; side++ {
+		fmt.Fprintf(out, "%s   %s\x1b[K\r\n", tuiFleet(g.Boards[side]), g.statsText(side.opponent()))
+	}
+	for _, s := range g.Events[max(0, len(g.Events)-tuiEvents):] {
+		fmt.Fprintf(out, "%s\x1b[K\r\n", s)
+	}
+	fmt.Fprint(out, "\r\n")
+	fmt.Fprint(out, g.tr("arrows: move, space: fire, q: quit"))
+	if g.PlacingMines {
+		fmt.Fprint(out, g.tr(", space: hide a mine, esc: hide the mines at random"))
+	}
+	if g.Rules.Moving {
+		fmt.Fprint(out, g.tr(", m: move a ship"))
+	}
+	fmt.Fprint(out, g.tr(", n: number the shots"))
+	fmt.Fprint(out, g.tr(", u: undo"))
+	fmt.Fprint(out, "\x1b[K\r\n")
+	if g.Announcer != nil && g.Announcer.Last != "" {
+		// The screen readers find the last event at the bottom.
+		fmt.Fprintf(out, "\r\n%s\x1b[K\r\n", g.Announcer.Last)
+	}
+	fmt.Fprint(out, "\x1b[J")
+}
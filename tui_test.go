@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{" ", []string{" "}},
+		{"\x1b", []string{"\x1b"}},
+		{"\x1b[A", []string{"\x1b[A"}},
+		{"\x1b[A\x1b[A", []string{"\x1b[A", "\x1b[A"}},
+		{"\x1b[C \x1b[Dq", []string{"\x1b[C", " ", "\x1b[D", "q"}},
+		{"\x1b[1;5A", []string{"\x1b[1;5A"}},
+		{"\x1b[", []string{"\x1b", "["}},
+		{"\x1b[12", []string{"\x1b[12"}},
+	}
+	for _, tt := range tests {
+		if got := splitKeys([]byte(tt.in)); !slices.Equal(got, tt.want) {
+			t.Errorf("splitKeys(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
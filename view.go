@@ -0,0 +1,117 @@
+package main
+
+import (
+	"slices"
+)
+
+// View is what a player knows about a board.
+// Views are derived from the board, which keeps the truth,
+// so that the AI and the renderer never see what the player may not know.
+type View struct {
+	Side  Side // the side of the board.
+	Cells [][]Cell
+	Ships []int   // number of remaining ships of size = idx+1
+	Scans []Scan  // radar scans done on the board.
+	Fleet []*Ship // the ships known to the viewer, for the opponent only the sunk ones.
+}
+
+// view returns what the viewer side knows about the board.
+// The owner knows everything. The opponent sees only the results of the shots,
+// the islands, and the sea around sunk ships; the rest is covered by the mist.
+func (b *Board) view(viewer Side) *View {
+	v := &View{
+		Side:  b.Side,
+		Cells: make([][]Cell, Ncells),
+		Ships: slices.Clone(b.Ships),
+		Scans: slices.Clone(b.Scans),
+	}
+	for y, row := range b.Cells {
+		v.Cells[y] = slices.Clone(row)
+	}
+	for _, s := range b.Fleet {
+		if viewer == b.Side || s.sunk() {
+			v.Fleet = append(v.Fleet, s.clone())
+		}
+	}
+	if viewer == b.Side {
+		return v
+	}
+	for _, row := range v.Cells {
+		for x, c := range row {
+			switch c {
+			case CellEmpty, CellShip, CellMine, CellOily:
+				row[x] = CellMist
+			}
+		}
+	}
+	for _, s := range b.Fleet {
+		if !s.sunk() {
+			continue
+		}
+		for _, xy := range s.around() {
+			if v.Cells[xy.Y][xy.X] == CellMist {
+				v.Cells[xy.Y][xy.X] = CellOily
+			}
+		}
+	}
+	return v
+}
+
+// shipAt returns the ship known to the viewer at the cell, or nil.
+func (v *View) shipAt(xy XY) *Ship {
+	return shipIn(v.Fleet, xy)
+}
+
+// unknown returns true if the viewer does not know yet what is in the cell.
+func (v *View) unknown(xy XY) bool {
+	return v.Cells[xy.Y][xy.X] == CellMist && !v.cleared(xy)
+}
+
+// cleared returns true if the radar has found no ships around the cell.
+func (v *View) cleared(xy XY) bool {
+	for _, s := range v.Scans {
+		if s.Found || s.Stale {
+			continue
+		}
+		if dx, dy := xy.X-s.Center.X, xy.Y-s.Center.Y; dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// contacts returns the unknown cells of the unresolved radar contacts,
+// i.e. the scans which found ships, where no ship was sunk yet.
+func (v *View) contacts() []XY {
+	var cells []XY
+	for _, s := range v.Scans {
+		if !s.Found || s.Stale {
+			continue
+		}
+		var unknown []XY
+		resolved := false
+		for _, t := range weaponArea(WeaponRadar, SideSelf, s.Center) {
+			if v.unknown(t) {
+				unknown = append(unknown, t)
+			} else if v.Cells[t.Y][t.X] == CellSunk {
+				resolved = true
+			}
+		}
+		if !resolved {
+			cells = append(cells, unknown...)
+		}
+	}
+	return cells
+}
+
+// fireCell returns the cell of a damaged but not yet sunk ship.
+func (v *View) fireCell() (XY, bool) {
+	for y := 0; y < Ncells; y++ {
+		for x := 0; x < Ncells; x++ {
+			if v.Cells[y][x] == CellFire {
+				return XY{x, y}, true
+			}
+		}
+	}
+	return XY{}, false
+}
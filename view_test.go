@@ -0,0 +1,76 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+var viewBoard = []string{
+	"##......",
+	"........",
+	"...^....",
+	"......+.",
+	"........",
+	"....#...",
+	"....#...",
+	"........",
+}
+
+func TestViewMasksOpponent(t *testing.T) {
+	g := testGame(t, Rules{Mines: 1}, emptyBoard, viewBoard)
+	b := g.Boards[SidePeer]
+	for _, xy := range []XY{{0, 0}, {1, 0}, {4, 5}, {7, 7}} {
+		b.hitCell(xy)
+	}
+	v := b.view(SideSelf)
+	want := []string{
+		"**.~~~~~", // the sea around the sunk ship.
+		"..~~~~~~",
+		"~~~^~~~~",
+		"~~~~~~~~", // the mine is hidden.
+		"~~~~~~~~",
+		"~~~~X~~~",
+		"~~~~~~~~", // and the rest of the damaged ship.
+		"~~~~~~~o",
+	}
+	if got := rows(v.Cells); !slices.Equal(got, want) {
+		t.Errorf("the opponent sees %q, want %q", got, want)
+	}
+	if len(v.Fleet) != 1 || v.Fleet[0].Cells[0] != (XY{0, 0}) {
+		t.Errorf("the opponent knows the fleet %v, want the sunk ship only", v.Fleet)
+	}
+	if v.shipAt(XY{4, 5}) != nil {
+		t.Error("the opponent knows the damaged ship")
+	}
+}
+
+func TestViewOwner(t *testing.T) {
+	g := testGame(t, Rules{Mines: 1}, emptyBoard, viewBoard)
+	b := g.Boards[SidePeer]
+	b.hitCell(XY{4, 5})
+	v := b.view(SidePeer)
+	if got, want := rows(v.Cells), rows(b.Cells); !slices.Equal(got, want) {
+		t.Errorf("the owner sees %q, want %q", got, want)
+	}
+	if len(v.Fleet) != 2 || v.shipAt(XY{4, 6}) == nil {
+		t.Errorf("the owner knows the fleet %v", v.Fleet)
+	}
+}
+
+func TestViewIsCopy(t *testing.T) {
+	g := testGame(t, Rules{Weapons: true}, emptyBoard, viewBoard)
+	b := g.Boards[SidePeer]
+	b.Scans = []Scan{{Center: XY{4, 5}, Found: true}}
+	for _, viewer := range []Side{SideSelf, SidePeer} {
+		v := b.view(viewer)
+		v.Cells[0][0] = CellMiss
+		v.Ships[1] = 0
+		v.Scans[0].Stale = true
+		for _, s := range v.Fleet {
+			s.Cells[0] = XY{7, 7}
+		}
+	}
+	if b.Cells[0][0] != CellShip || b.Ships[1] != 2 || b.Scans[0].Stale || b.Fleet[1].Cells[0] != (XY{4, 5}) {
+		t.Errorf("the views have changed the board: %q, ships %v, scans %v", rows(b.Cells), b.Ships, b.Scans)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type Weapon int
+
+type WeaponParams struct {
+	Name     string
+	Charges  int // -1 means unlimited.
+	Cooldown int // number of own turns until the weapon is ready again.
+}
+
+// Arsenal keeps the state of the weapons of one side.
+type Arsenal struct {
+	Selected Weapon
+	Charges  [numWeapons]int
+	Cooldown [numWeapons]int
+}
+
+// Scan is the result of the radar scan on the board.
+type Scan struct {
+	Center XY
+	Found  bool // whether any ship was found in the area.
+	Stale  bool // whether ships have moved since the scan.
+}
+
+const (
+	WeaponShot    Weapon = iota // single cell shot
+	WeaponRadar                 // reveals whether any ship is in 3x3 area
+	WeaponTorpedo               // travels along a row until it hits
+	WeaponCluster               // plus-shaped bomb
+	numWeapons
+)
+
+var (
+	weaponParams = [numWeapons]WeaponParams{
+		WeaponShot:    {"Shot", -1, 0},
+		WeaponRadar:   {"Radar", 2, 3},
+		WeaponTorpedo: {"Torpedo", 1, 0},
+		WeaponCluster: {"Cluster", 2, 4},
+	}
+)
+
+func (w Weapon) String() string {
+	return weaponParams[w].Name
+}
+
+// parseWeapon returns the weapon by its name, case insensitive.
+func parseWeapon(name string) (Weapon, bool) {
+	for w := Weapon(0); w < numWeapons; w++ {
+		if strings.EqualFold(name, w.String()) {
+			return w, true
+		}
+	}
+	return 0, false
+}
+
+func NewArsenal() *Arsenal {
+	a := &Arsenal{}
+	for w := range a.Charges {
+		a.Charges[w] = weaponParams[w].Charges
+	}
+	return a
+}
+
+// ready returns true if the weapon has charges and is not cooling down.
+func (a *Arsenal) ready(w Weapon) bool {
+	return a.Charges[w] != 0 && a.Cooldown[w] == 0
+}
+
+// use spends a charge of the weapon w and starts its cooldown.
+func (a *Arsenal) use(w Weapon) error {
+	if !a.ready(w) {
+		return fmt.Errorf("%s is not ready", w)
+	}
+	if a.Charges[w] > 0 {
+		a.Charges[w]--
+	}
+	a.Cooldown[w] = weaponParams[w].Cooldown
+	if !a.ready(a.Selected) {
+		a.Selected = WeaponShot
+	}
+	return nil
+}
+
+// cool advances the cooldowns at the start of every own turn, even the skipped one.
+func (a *Arsenal) cool() {
+	for i := range a.Cooldown {
+		if a.Cooldown[i] > 0 {
+			a.Cooldown[i]--
+		}
+	}
+	if !a.ready(a.Selected) {
+		a.Selected = WeaponShot
+	}
+}
+
+// status returns the text describing the weapon state in the language.
+func (a *Arsenal) status(l *Lang, w Weapon) string {
+	name := l.tr(w.String())
+	if a.Charges[w] < 0 {
+		return name
+	} else if a.Charges[w] == 0 {
+		return l.tr("%s: no charges left", name)
+	}
+	left := l.trn(a.Charges[w], "%s: %d charge left", "%s: %d charges left", name, a.Charges[w])
+	if a.Cooldown[w] > 0 {
+		return l.trn(a.Cooldown[w], "%s, ready in %d turn", "%s, ready in %d turns", left, a.Cooldown[w])
+	}
+	return left
+}
+
+// weaponArea returns the cells affected by the weapon w fired by the side at xy.
+// The torpedo cells are returned in the order of its travel.
+func weaponArea(w Weapon, side Side, xy XY) []XY {
+	var area []XY
+	add := func(x, y int) {
+		if x >= 0 && x < Ncells && y >= 0 && y < Ncells {
+			area = append(area, XY{x, y})
+		}
+	}
+	switch w {
+	case WeaponRadar:
+		for y := xy.Y - 1; y <= xy.Y+1; y++ {
+			for x := xy.X - 1; x <= xy.X+1; x++ {
+				add(x, y)
+			}
+		}
+	case WeaponTorpedo:
+		// The torpedo is launched from the board edge closest to the shooter.
+		if side == SideSelf {
+			for x := 0; x < Ncells; x++ {
+				add(x, xy.Y)
+			}
+		} else {
+			for x := Ncells - 1; x >= 0; x-- {
+				add(x, xy.Y)
+			}
+		}
+	case WeaponCluster:
+		add(xy.X, xy.Y)
+		add(xy.X-1, xy.Y)
+		add(xy.X+1, xy.Y)
+		add(xy.X, xy.Y-1)
+		add(xy.X, xy.Y+1)
+	default:
+		add(xy.X, xy.Y)
+	}
+	return area
+}
+
+// shoot fires the weapon w of the side at xy on the opponent board, and records it.
+// It returns true if the side may shoot again.
+func (g *Game) shoot(side Side, w Weapon, xy XY) (bool, error) {
+	if !g.Rules.Weapons {
+		w = WeaponShot
+	}
+	b, own := g.Boards[side.opponent()], g.Boards[side]
+	// The mine hit by the shot may damage own ship of the side.
+	before, ownBefore := b.view(b.Side), own.view(own.Side)
+	var seen, ownSeen *View
+	if g.Animate {
+		seen, ownSeen = b.view(SideSelf), own.view(SideSelf)
+	}
+	again, err := g.fire(side, w, xy)
+	if err != nil {
+		return false, err
+	}
+	after, ownAfter := b.view(b.Side), own.view(own.Side)
+	results, cells := viewChanges(before, after)
+	blasts, blasted := viewChanges(ownBefore, ownAfter)
+	for _, r := range blasts {
+		results = append(results, "blast "+r)
+	}
+	g.record(side, Action{Weapon: w, Target: xy}, results, cells)
+	land := g.Tick
+	if g.Animate {
+		g.animateShot(side, xy, before, seen, after)
+		land += effectTicks[EffectFlight]
+		g.animateCells(side, land+effectTicks[EffectExplosion], ownBefore, ownSeen, ownAfter)
+	}
+	pan := 0.0
+	if g.Settings.Cues {
+		// The shots are heard where they land.
+		pan = cuePan(xy)
+	}
+	if s, ok := shotSound(before, after); ok {
+		g.playSound(s, land, pan)
+	}
+	if s, ok := shotSound(ownBefore, ownAfter); ok {
+		if g.Settings.Cues {
+			pan = cuePan(blasted[0])
+		}
+		g.playSound(s, land+effectTicks[EffectExplosion], pan)
+	}
+	shot := g.shotText(side, w, xy, before, after)
+	if blast := g.blastText(side, ownBefore, ownAfter); blast != "" {
+		shot += ", " + blast
+	}
+	g.Events = append(g.Events, shot)
+	g.announce("%s", shot)
+	return again, nil
+}
+
+// fire fires the weapon w of the side at xy on the opponent board.
+// It returns true if the side may shoot again, or the error if the weapon is not ready.
+func (g *Game) fire(side Side, w Weapon, xy XY) (bool, error) {
+	b := g.Boards[side.opponent()]
+	if !g.Rules.Weapons {
+		return b.hitCell(xy), nil
+	}
+	if err := g.Arsenals[side].use(w); err != nil {
+		return false, err
+	}
+	area := weaponArea(w, side, xy)
+	switch w {
+	case WeaponRadar:
+		b.scan(xy, area)
+		return false, nil
+	case WeaponTorpedo:
+		for _, t := range area {
+			switch c := b.Cells[t.Y][t.X]; c {
+			case CellShip, CellMine:
+				return b.hitCell(t), nil
+			case CellRock:
+				return false, nil
+			case CellEmpty:
+				b.Cells[t.Y][t.X] = CellMiss
+			}
+		}
+		return false, nil
+	case WeaponCluster:
+		again := false
+		for _, t := range area {
+			if b.Cells[t.Y][t.X] == CellShip {
+				again = true
+			}
+			b.hitCell(t)
+		}
+		return again, nil
+	}
+	return b.hitCell(xy), nil
+}
+
+// scan checks the area around xy for ships.
+// Mines are not detected by the radar.
+func (b *Board) scan(xy XY, area []XY) {
+	found := false
+	for _, t := range area {
+		if b.Cells[t.Y][t.X] == CellShip {
+			found = true
+		}
+	}
+	b.Scans = append(b.Scans, Scan{Center: xy, Found: found})
+	g := b.Game
+	if found {
+		g.Message = g.tr("Radar: contact near %s", g.Lang.cell(xy))
+	} else {
+		g.Message = g.tr("Radar: no ships near %s", g.Lang.cell(xy))
+	}
+}
+
+// drawScans draws the outlines of the radar scans in the view.
+func (b *Board) drawScans(screen *ebiten.Image, v *View) {
+	for _, s := range v.Scans {
+		col := color.RGBA(b.Game.Theme.Clear)
+		if s.Found {
+			col = color.RGBA(b.Game.Theme.Contact)
+		}
+		if s.Stale {
+			col.A /= 3
+		}
+		x, y := b.Game.Grid.cellOrigin(s.Center.X-1, s.Center.Y-1, b.Side)
+		size := float32(3*cellSize + 2*cellBorder)
+		vector.StrokeRect(screen, x, y, size, size, 2, col, false)
+	}
+}
+
+// selectWeapon selects the weapon of the player if it is ready.
+func (g *Game) selectWeapon(w Weapon) {
+	a := g.Arsenals[SideSelf]
+	g.Message = a.status(g.Lang, w)
+	if a.ready(w) {
+		a.Selected = w
+	}
+}
+
+// weaponAt returns the weapon in the weapon bar at the screen position.
+func (g *Game) weaponAt(px, py int) (Weapon, bool) {
+	xy := g.Grid.cellAt(px, py, SidePeer)
+	if xy.Y != Ncells+1 || xy.X < 0 || xy.X >= int(numWeapons) {
+		return 0, false
+	}
+	return Weapon(xy.X), true
+}
+
+// drawWeapons draws the weapon bar under the peer board.
+func (g *Game) drawWeapons(screen *ebiten.Image) {
+	a := g.Arsenals[SideSelf]
+	for w := Weapon(0); w < numWeapons; w++ {
+		x, y := g.Grid.cellOrigin(int(w), Ncells+1, SidePeer)
+		col := g.Theme.Cells[CellShip].Fill
+		if !a.ready(w) {
+			col = g.Theme.Cells[CellMist].Fill
+		}
+		vector.DrawFilledRect(screen, x, y, cellSize, cellSize, color.RGBA(col), false)
+		if w == a.Selected {
+			vector.StrokeRect(screen, x, y, cellSize, cellSize, 2, color.RGBA(g.Theme.Cursor), false)
+		}
+		label := string([]rune(g.tr(w.String()))[:1])
+		if a.Charges[w] >= 0 {
+			label = fmt.Sprintf("%s%d", label, a.Charges[w])
+		}
+		text.Draw(screen, label, g.Theme.face(cellSize*0.5), g.textInXY(int(w), Ncells+1, SidePeer))
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWeaponArea(t *testing.T) {
+	for _, tc := range []struct {
+		w    Weapon
+		side Side
+		at   XY
+		want []XY
+	}{
+		{WeaponShot, SideSelf, XY{3, 4}, []XY{{3, 4}}},
+		{WeaponCluster, SideSelf, XY{3, 4}, []XY{{3, 4}, {2, 4}, {4, 4}, {3, 3}, {3, 5}}},
+		// Clipped at the edges.
+		{WeaponCluster, SideSelf, XY{0, 0}, []XY{{0, 0}, {1, 0}, {0, 1}}},
+		{WeaponCluster, SidePeer, XY{7, 7}, []XY{{7, 7}, {6, 7}, {7, 6}}},
+		{WeaponRadar, SideSelf, XY{0, 7}, []XY{{0, 6}, {1, 6}, {0, 7}, {1, 7}}},
+		// The torpedo travels from the edge of the shooter.
+		{WeaponTorpedo, SideSelf, XY{5, 2}, []XY{{0, 2}, {1, 2}, {2, 2}, {3, 2}, {4, 2}, {5, 2}, {6, 2}, {7, 2}}},
+		{WeaponTorpedo, SidePeer, XY{5, 2}, []XY{{7, 2}, {6, 2}, {5, 2}, {4, 2}, {3, 2}, {2, 2}, {1, 2}, {0, 2}}},
+	} {
+		if got := weaponArea(tc.w, tc.side, tc.at); !slices.Equal(got, tc.want) {
+			t.Errorf("weaponArea(%s, %d, %s) = %v, want %v", tc.w, tc.side, tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestFire(t *testing.T) {
+	peer := []string{
+		"...##...",
+		"........",
+		"..^..#..",
+		"........",
+		"...+..#.",
+		"........",
+		"#.......",
+		"#.......",
+	}
+	for _, tc := range []struct {
+		name  string
+		w     Weapon
+		at    XY
+		again bool
+		want  []string // the rows changed.
+		skip  bool     // whether the mine is hit.
+	}{
+		{"torpedo stops at the ship", WeaponTorpedo, XY{0, 0}, true, []string{"oooX#..."}, false},
+		{"torpedo stops at the island", WeaponTorpedo, XY{7, 2}, false, []string{"oo^..#.."}, false},
+		{"torpedo stops at the mine", WeaponTorpedo, XY{0, 4}, false, []string{"ooo!..#."}, true},
+		{"cluster clipped at the corner", WeaponCluster, XY{0, 7}, true, []string{"*.......", "*o......"}, false},
+		{"cluster missing", WeaponCluster, XY{5, 6}, false, []string{".....o..", "#...ooo.", "#....o.."}, false},
+		{"shot", WeaponShot, XY{6, 4}, true, []string{"...+..*."}, false},
+	} {
+		g := testGame(t, Rules{Weapons: true}, emptyBoard, peer)
+		b := g.Boards[SidePeer]
+		before := rows(b.Cells)
+		again, err := g.fire(SideSelf, tc.w, tc.at)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if again != tc.again {
+			t.Errorf("%s: again %v, want %v", tc.name, again, tc.again)
+		}
+		var changed []string
+		for y, row := range rows(b.Cells) {
+			if row != before[y] {
+				changed = append(changed, row)
+			}
+		}
+		if !slices.Equal(changed, tc.want) {
+			t.Errorf("%s: changed rows %q, want %q", tc.name, changed, tc.want)
+		}
+		if g.Skips[SideSelf] != tc.skip {
+			t.Errorf("%s: skips the turn %v, want %v", tc.name, g.Skips[SideSelf], tc.skip)
+		}
+	}
+}
+
+func TestRadar(t *testing.T) {
+	g := testGame(t, Rules{Weapons: true}, emptyBoard, []string{
+		"........",
+		"........",
+		"..+.....",
+		"........",
+		"........",
+		"......##",
+		"........",
+		"........",
+	})
+	b := g.Boards[SidePeer]
+	before := rows(b.Cells)
+	for _, tc := range []struct {
+		at    XY
+		found bool
+	}{
+		{XY{2, 2}, false}, // the mine is not found.
+		{XY{5, 4}, true},
+		{XY{7, 7}, false},
+	} {
+		g.Arsenals[SideSelf] = NewArsenal()
+		if again, err := g.fire(SideSelf, WeaponRadar, tc.at); err != nil || again {
+			t.Fatalf("radar at %s: again %v, %v", tc.at, again, err)
+		}
+		if sc := b.Scans[len(b.Scans)-1]; sc.Center != tc.at || sc.Found != tc.found {
+			t.Errorf("radar at %s: got %+v, want found %v", tc.at, sc, tc.found)
+		}
+	}
+	if got := rows(b.Cells); !slices.Equal(got, before) {
+		t.Errorf("the radar has changed the board: %q", got)
+	}
+	if v := b.view(SideSelf); len(v.Scans) != 3 {
+		t.Errorf("the opponent sees %d scans, want 3", len(v.Scans))
+	}
+}
+
+func TestArsenalCooldown(t *testing.T) {
+	a := NewArsenal()
+	a.Selected = WeaponCluster
+	if err := a.use(WeaponCluster); err != nil {
+		t.Fatal(err)
+	}
+	if a.Selected != WeaponShot || a.Charges[WeaponCluster] != 1 {
+		t.Errorf("after the cluster: selected %s, %d charges", a.Selected, a.Charges[WeaponCluster])
+	}
+	for turn := 1; turn <= weaponParams[WeaponCluster].Cooldown; turn++ {
+		if err := a.use(WeaponCluster); err == nil {
+			t.Fatalf("the cluster is used again in %d turns", turn)
+		}
+		a.cool()
+	}
+	if err := a.use(WeaponCluster); err != nil {
+		t.Errorf("after the cooldown: %v", err)
+	}
+	a.Cooldown[WeaponCluster] = 0
+	if err := a.use(WeaponCluster); err == nil {
+		t.Error("the cluster is used without charges")
+	}
+	if err := a.use(WeaponShot); err != nil || a.Charges[WeaponShot] != -1 {
+		t.Errorf("the shot: %v, %d charges", err, a.Charges[WeaponShot])
+	}
+}
+
+func TestNextTurnCools(t *testing.T) {
+	g := testGame(t, Rules{Weapons: true}, emptyBoard, emptyBoard)
+	if err := g.Arsenals[SideSelf].use(WeaponCluster); err != nil {
+		t.Fatal(err)
+	}
+	side := SideSelf
+	for turn := 1; turn <= weaponParams[WeaponCluster].Cooldown; turn++ {
+		side = g.nextTurn(g.nextTurn(side))
+		if ready := g.Arsenals[SideSelf].ready(WeaponCluster); ready != (turn == weaponParams[WeaponCluster].Cooldown) {
+			t.Errorf("the cluster is ready %v after %d turns", ready, turn)
+		}
+	}
+	if side != SideSelf {
+		t.Errorf("side %d plays after the rounds", side)
+	}
+}
+
+func TestFireNotReady(t *testing.T) {
+	g := testGame(t, Rules{Weapons: true}, emptyBoard, emptyBoard)
+	g.Arsenals[SideSelf].Charges[WeaponTorpedo] = 0
+	if _, err := g.fire(SideSelf, WeaponTorpedo, XY{0, 0}); err == nil {
+		t.Error("fired the torpedo without charges")
+	}
+	if got := rows(g.Boards[SidePeer].Cells); !slices.Equal(got, emptyBoard) {
+		t.Errorf("the board has changed: %q", got)
+	}
+}